@@ -0,0 +1,155 @@
+package msvc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jansemmelink/config"
+	"github.com/jansemmelink/log"
+)
+
+//AuditRecord is written to the audit sinks for every transaction handled by the micro-service
+type AuditRecord struct {
+	Service   string        `json:"service" doc:"Name of the micro-service."`
+	Oper      string        `json:"oper" doc:"Name of the operation that was requested."`
+	UUID      string        `json:"uuid" doc:"UUID of the request, as specified by the consumer or assigned by the micro-service."`
+	Consumer  *Consumer     `json:"consumer,omitempty" doc:"Consumer as specified in the request header."`
+	Provider  *Provider     `json:"provider,omitempty" doc:"Provider as written in the response header."`
+	StartTime time.Time     `json:"start-time" doc:"Time when the request was received."`
+	EndTime   time.Time     `json:"end-time" doc:"Time when the response was sent."`
+	Dur       time.Duration `json:"duration" doc:"Duration from start-time to end-time."`
+	Result    string        `json:"result" doc:"\"success\" or the type of error returned."`
+	Error     *Error        `json:"error,omitempty" doc:"Error returned to the consumer, if not successful."`
+	Audit     interface{}   `json:"audit,omitempty" doc:"Optional audit data provided by the operation (see IAuditor)."`
+}
+
+//auditResultSuccess is the AuditRecord.Result when no error was returned
+const auditResultSuccess = "success"
+
+//IAuditor may be implemented by an operation to add its own data to the audit record.
+//Audit() is called after the operation completed
+type IAuditor interface {
+	Audit() interface{}
+}
+
+//IAuditSink writes audit records, e.g. to a file or a message queue
+type IAuditSink interface {
+	//sink must be configurable, so embed this:
+	config.IValidator
+
+	//Open is called before records are written for the named service
+	Open(serviceName string) error
+
+	//Write one audit record. It may be called concurrently.
+	Write(record AuditRecord) error
+
+	//Close is called when the service terminates
+	Close() error
+}
+
+//RegisterAuditSink must be called in the audit sink implementation's init() func
+//to make it available to the micro-service framework. It will be opened when the
+//service is served if configuration "audit-<name>" is present in the config set
+func RegisterAuditSink(name string, tmpl IAuditSink) {
+	if len(name) == 0 || tmpl == nil {
+		panic("Audit sink registration must have a name")
+	}
+
+	auditSinkMutex.Lock()
+	defer auditSinkMutex.Unlock()
+
+	if _, ok := auditSinkTmpl[name]; ok {
+		panic("Duplicate audit sink name")
+	}
+	auditSinkTmpl[name] = tmpl
+}
+
+var (
+	auditSinkMutex sync.Mutex
+	auditSinkTmpl  = make(map[string]IAuditSink)
+)
+
+//auditor writes audit records to all the open sinks
+type auditor struct {
+	mutex sync.RWMutex
+	sinks map[string]IAuditSink
+}
+
+func newAuditor() *auditor {
+	return &auditor{
+		sinks: make(map[string]IAuditSink),
+	}
+}
+
+//open all the audit sinks that are configured in the config set
+func (a *auditor) open(cs config.ISet, serviceName string) {
+	auditSinkMutex.Lock()
+	defer auditSinkMutex.Unlock()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	log.Debugf("Trying %d audit sink configurations...", len(auditSinkTmpl))
+	for sinkName, tmpl := range auditSinkTmpl {
+		sinkConfiguration, err := cs.Add("audit-"+sinkName, tmpl)
+		if err != nil {
+			log.Debugf("audit sink[%s] not configured: %+v", sinkName, err)
+			continue
+		}
+
+		configuredSink := sinkConfiguration.Current().(IAuditSink)
+		if err := configuredSink.Open(serviceName); err != nil {
+			panic(log.Wrapf(err, "Failed to open audit sink %s", sinkName))
+		}
+		a.sinks[sinkName] = configuredSink
+		log.Debugf("Opened audit sink (%T)%+v", configuredSink, configuredSink)
+	}
+} //auditor.open()
+
+//write the record to all open sinks
+func (a *auditor) write(record AuditRecord) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	for sinkName, sink := range a.sinks {
+		if err := sink.Write(record); err != nil {
+			log.Errorf("Failed to write audit record %s to sink %s: %+v", record.UUID, sinkName, err)
+		}
+	}
+}
+
+//close all open sinks
+func (a *auditor) close() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for sinkName, sink := range a.sinks {
+		if err := sink.Close(); err != nil {
+			log.Errorf("Failed to close audit sink %s: %+v", sinkName, err)
+		}
+		delete(a.sinks, sinkName)
+	}
+}
+
+//audit writes the record for one transaction
+func (msvc msvc) audit(operName string, startTime time.Time, requestMessage RequestMessage, responseMessage ResponseMessage, auditData interface{}) {
+	endTime := time.Now()
+	record := AuditRecord{
+		Service:   msvc.name,
+		Oper:      operName,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Dur:       endTime.Sub(startTime),
+		Result:    auditResultSuccess,
+		Error:     responseMessage.Error,
+		Audit:     auditData,
+	}
+	if requestMessage.Header != nil {
+		record.Consumer = requestMessage.Header.Consumer
+	}
+	if responseMessage.Header != nil {
+		record.UUID = responseMessage.Header.UUID
+		record.Provider = responseMessage.Header.Provider
+	}
+	if responseMessage.Error != nil {
+		record.Result = responseMessage.Error.Type
+	}
+	msvc.auditor.write(record)
+} //msvc.audit()
@@ -0,0 +1,141 @@
+//Package file implements a msvc.IAuditSink that writes audit records as JSON lines
+//to a file that is rotated when it reaches a maximum size, e.g. with conf/audit-file.json:
+//
+//	{"path":"./audit/template.jsonl","max-size":10485760,"max-files":5}
+//
+//When the file reaches max-size, it is renamed to <path>.1 and older files
+//are renamed to <path>.2 ... <path>.<max-files> after which they are deleted.
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+)
+
+//fileSink implements msvc.IAuditSink to write audit records to a rotating file
+type fileSink struct {
+	Path     string `json:"path" doc:"Name of the audit file. Defaults to \"./audit/<service>.jsonl\""`
+	MaxSize  int64  `json:"max-size" doc:"Size in bytes when the file is rotated. Defaults to 10MB."`
+	MaxFiles int    `json:"max-files" doc:"Number of rotated files to keep. Defaults to 5."`
+
+	//run-time private data:
+	mutex *sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+const (
+	defaultMaxSize  = 10 * 1024 * 1024
+	defaultMaxFiles = 5
+)
+
+func (fs *fileSink) Validate() error {
+	if fs.MaxSize < 0 {
+		return log.Wrapf(nil, "Negative max-size")
+	}
+	if fs.MaxSize == 0 {
+		fs.MaxSize = defaultMaxSize
+	}
+	if fs.MaxFiles < 0 {
+		return log.Wrapf(nil, "Negative max-files")
+	}
+	if fs.MaxFiles == 0 {
+		fs.MaxFiles = defaultMaxFiles
+	}
+	log.Debugf("Validated %T", fs)
+	return nil
+}
+
+func (fs *fileSink) Open(serviceName string) error {
+	if len(fs.Path) == 0 {
+		fs.Path = filepath.Join(".", "audit", serviceName+".jsonl")
+	}
+	if err := os.MkdirAll(filepath.Dir(fs.Path), 0755); err != nil {
+		return log.Wrapf(err, "Failed to create audit directory")
+	}
+	fs.mutex = &sync.Mutex{}
+	return fs.openFile()
+}
+
+//openFile opens the audit file to append records
+func (fs *fileSink) openFile() error {
+	file, err := os.OpenFile(fs.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return log.Wrapf(err, "Failed to open audit file %s", fs.Path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return log.Wrapf(err, "Failed to stat audit file %s", fs.Path)
+	}
+	fs.file = file
+	fs.size = info.Size()
+	return nil
+} //fileSink.openFile()
+
+func (fs *fileSink) Write(record msvc.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return log.Wrapf(err, "Failed to encode audit record")
+	}
+	line = append(line, '\n')
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if fs.file == nil {
+		return log.Wrapf(nil, "Audit file %s is not open", fs.Path)
+	}
+	if fs.size > 0 && fs.size+int64(len(line)) > fs.MaxSize {
+		if err := fs.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := fs.file.Write(line)
+	fs.size += int64(n)
+	if err != nil {
+		return log.Wrapf(err, "Failed to write audit file %s", fs.Path)
+	}
+	return nil
+} //fileSink.Write()
+
+//rotate closes the current file, renames it to <path>.1 after renaming older files
+//and opens a new file. Must be called with the mutex locked.
+func (fs *fileSink) rotate() error {
+	if err := fs.file.Close(); err != nil {
+		log.Errorf("Failed to close audit file %s: %+v", fs.Path, err)
+	}
+	fs.file = nil
+
+	//delete the oldest and shift the others up
+	os.Remove(fmt.Sprintf("%s.%d", fs.Path, fs.MaxFiles))
+	for i := fs.MaxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", fs.Path, i), fmt.Sprintf("%s.%d", fs.Path, i+1))
+	}
+	if err := os.Rename(fs.Path, fs.Path+".1"); err != nil {
+		log.Errorf("Failed to rotate audit file %s: %+v", fs.Path, err)
+	}
+	return fs.openFile()
+} //fileSink.rotate()
+
+func (fs *fileSink) Close() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
+
+func init() {
+	//register &<struct>{} so that Validate() method will be called with pointer receiver
+	//and be able to set defaults
+	msvc.RegisterAuditSink("file", &fileSink{})
+}
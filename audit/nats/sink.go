@@ -0,0 +1,67 @@
+//Package nats implements a msvc.IAuditSink that publishes audit records as JSON
+//on a NATS subject, e.g. with conf/audit-nats.json:
+//
+//	{"url":"localhost:4222","subject":"audit.template"}
+package nats
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+	"github.com/nats-io/nats.go"
+)
+
+//natsSink implements msvc.IAuditSink to publish audit records on a NATS subject
+type natsSink struct {
+	URL     string `json:"url" doc:"URL of NATS server. Defaults to \"localhost:4222\""`
+	Subject string `json:"subject" doc:"Subject to publish audit records on. Defaults to \"audit.<service>\""`
+
+	//run-time private data:
+	conn *nats.Conn
+}
+
+func (ns *natsSink) Validate() error {
+	if len(ns.URL) == 0 {
+		ns.URL = "localhost:4222"
+	}
+	log.Debugf("Validated %T", ns)
+	return nil
+}
+
+func (ns *natsSink) Open(serviceName string) error {
+	if len(ns.Subject) == 0 {
+		ns.Subject = "audit." + serviceName
+	}
+	conn, err := nats.Connect(ns.URL,
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(time.Second*2))
+	if err != nil {
+		return log.Wrapf(err, "Failed to connect to NATS server %s", ns.URL)
+	}
+	ns.conn = conn
+	return nil
+}
+
+func (ns *natsSink) Write(record msvc.AuditRecord) error {
+	jsonRecord, err := json.Marshal(record)
+	if err != nil {
+		return log.Wrapf(err, "Failed to encode audit record")
+	}
+	if err := ns.conn.Publish(ns.Subject, jsonRecord); err != nil {
+		return log.Wrapf(err, "Failed to publish audit record on %s", ns.Subject)
+	}
+	return nil
+}
+
+func (ns *natsSink) Close() error {
+	//drain flushes records not yet published before the connection is closed
+	return ns.conn.Drain()
+}
+
+func init() {
+	//register &<struct>{} so that Validate() method will be called with pointer receiver
+	//and be able to set defaults
+	msvc.RegisterAuditSink("nats", &natsSink{})
+}
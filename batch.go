@@ -0,0 +1,92 @@
+package msvc
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/jansemmelink/log"
+)
+
+//BatchOperName is the reserved operation name that servers use for batch requests,
+//e.g. POST /<service>/_batch in the rest server or subject <service>._batch in nats
+const BatchOperName = "_batch"
+
+//BatchRequest is a list of requests sent in one message
+type BatchRequest struct {
+	Parallel bool        `json:"parallel,omitempty" doc:"Run the items at the same time. By default they run sequentially in order."`
+	Items    []BatchItem `json:"items" doc:"Requests to process."`
+}
+
+//BatchItem is one request in a batch with the same header and request data
+//as a RequestMessage sent to the operation
+type BatchItem struct {
+	Oper    string          `json:"oper" doc:"Name of the operation."`
+	Header  json.RawMessage `json:"header,omitempty" doc:"Request header, as in a request message."`
+	Request json.RawMessage `json:"request,omitempty" doc:"Request data of the operation."`
+}
+
+//BatchResponse has the responses to the batch items in the same order.
+//A failed item does not fail the batch: its error is in the item's response message.
+type BatchResponse struct {
+	Items []ResponseMessage `json:"items,omitempty" doc:"Response messages in the order of the batch items."`
+	Error *Error            `json:"error,omitempty" doc:"Error when the batch request could not be processed."`
+}
+
+//HandleBatchJSON is called by IServer implementations for BatchOperName.
+//Each item passes through HandleJSON() as if it was sent on its own
+func (msvc msvc) HandleBatchJSON(jsonBatchRequest []byte) BatchResponse {
+	var batchRequest BatchRequest
+	if err := json.Unmarshal(jsonBatchRequest, &batchRequest); err != nil {
+		return BatchResponse{
+			Error: &Error{
+				Type:        "decodeJSONBatch",
+				Description: log.Wrapf(err, "Failed to decode batch request").Error(),
+			},
+		}
+	}
+
+	batchResponse := BatchResponse{
+		Items: make([]ResponseMessage, len(batchRequest.Items)),
+	}
+	handleItem := func(i int, item BatchItem) {
+		jsonRequestMessage, err := json.Marshal(batchItemMessage{
+			Header:  item.Header,
+			Request: item.Request,
+		})
+		if err != nil {
+			//only when item.Header or item.Request was modified after decoding
+			batchResponse.Items[i] = ResponseMessage{
+				Error: &Error{
+					Type:        "decodeJSONRequestHeader",
+					Description: log.Wrapf(err, "Failed to encode batch item %d", i).Error(),
+				},
+			}
+			return
+		}
+		batchResponse.Items[i] = msvc.HandleJSON(item.Oper, jsonRequestMessage)
+	}
+
+	if !batchRequest.Parallel {
+		for i, item := range batchRequest.Items {
+			handleItem(i, item)
+		}
+		return batchResponse
+	}
+
+	wg := sync.WaitGroup{}
+	for i, item := range batchRequest.Items {
+		wg.Add(1)
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+			handleItem(i, item)
+		}(i, item)
+	}
+	wg.Wait()
+	return batchResponse
+} //msvc.HandleBatchJSON()
+
+//batchItemMessage is the request message passed to HandleJSON() for a batch item
+type batchItemMessage struct {
+	Header  json.RawMessage `json:"header,omitempty"`
+	Request json.RawMessage `json:"request,omitempty"`
+}
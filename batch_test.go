@@ -0,0 +1,73 @@
+package msvc_test
+
+import (
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+)
+
+//echoOper responds with the value in the request
+type echoOper struct {
+	msvc.Oper
+	Value string `json:"value" validate:"required"`
+}
+
+func (o echoOper) Results() []msvc.IResult { return nil }
+
+func (o echoOper) Run() (interface{}, *msvc.Error) {
+	return o.Value, nil
+}
+
+func TestBatch(t *testing.T) {
+	items := `[
+		{"oper":"echo","request":{"value":"a"}},
+		{"oper":"echo","request":{}},
+		{"oper":"missing","request":{"value":"c"}},
+		{"oper":"echo","request":{"value":"d"}}
+	]`
+	expected := []struct {
+		response  interface{}
+		errorType string
+	}{
+		{response: "a"},
+		{errorType: "invalidRequest"},
+		{errorType: "unknownOper"},
+		{response: "d"},
+	}
+	for _, test := range []struct {
+		name             string
+		jsonBatchRequest string
+	}{
+		{name: "sequential", jsonBatchRequest: `{"items":` + items + `}`},
+		{name: "parallel", jsonBatchRequest: `{"parallel":true,"items":` + items + `}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			svc := msvc.New("test").WithOper("echo", echoOper{})
+			batchResponse := svc.HandleBatchJSON([]byte(test.jsonBatchRequest))
+			if batchResponse.Error != nil {
+				t.Fatalf("Expected batch to succeed but got %+v", batchResponse.Error)
+			}
+			if len(batchResponse.Items) != len(expected) {
+				t.Fatalf("Expected %d items but got %d", len(expected), len(batchResponse.Items))
+			}
+			for i, item := range batchResponse.Items {
+				switch {
+				case expected[i].errorType != "":
+					if item.Error == nil || item.Error.Type != expected[i].errorType {
+						t.Errorf("Item %d: expected error %s but got %+v", i, expected[i].errorType, item.Error)
+					}
+				case item.Error != nil:
+					t.Errorf("Item %d: expected success but got %+v", i, item.Error)
+				case item.Response != expected[i].response:
+					t.Errorf("Item %d: expected response %v but got %v", i, expected[i].response, item.Response)
+				}
+			}
+		})
+	}
+
+	//a batch that cannot be decoded fails as a whole
+	svc := msvc.New("test").WithOper("echo", echoOper{})
+	if batchResponse := svc.HandleBatchJSON([]byte(`{"items":`)); batchResponse.Error == nil || batchResponse.Error.Type != "decodeJSONBatch" {
+		t.Fatalf("Expected decodeJSONBatch but got %+v", batchResponse.Error)
+	}
+}
@@ -0,0 +1,147 @@
+//Package client is used to call operations of other micro-services.
+//It builds the request message envelope, sends it with a transport such as
+//client/nats or client/rest, and decodes the response message, e.g.:
+//
+//	c := nats.New(conn, "template")
+//	var greeting string
+//	if err := c.Call(ctx, "hello", map[string]string{"name": "Jan"}, &greeting); err != nil {
+//		if e, ok := err.(*msvc.Error); ok {
+//			//the operation failed with e.Type
+//		}
+//	}
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+)
+
+//ITransport sends request messages to a micro-service
+type ITransport interface {
+	//Send the JSON request message to the operation and return the JSON response message
+	Send(ctx context.Context, serviceName string, operName string, jsonRequestMessage []byte) ([]byte, error)
+}
+
+//IClient calls the operations of one micro-service
+type IClient interface {
+	//WithConsumer sets the consumer in request headers.
+	//By default, the consumer name is the name of the service in ctx (see msvc.ServiceFromContext())
+	WithConsumer(consumer msvc.Consumer) IClient
+
+	//WithMaxDuration sets the max-duration of requests when ctx has no deadline
+	WithMaxDuration(maxDur time.Duration) IClient
+
+	//Call sends the request data to the named operation and decodes the response data into response,
+	//which must be a pointer or nil to ignore response data. It returns *msvc.Error when the operation
+	//failed, context.DeadlineExceeded when the ctx deadline passed before the request was sent,
+	//or another error when the request could not be sent or the response could not be decoded
+	Call(ctx context.Context, operName string, request interface{}, response interface{}) error
+
+	//CallMessage is like Call() but returns the whole response message with the header
+	CallMessage(ctx context.Context, operName string, request interface{}, response interface{}) (msvc.ResponseMessage, error)
+}
+
+//New creates a client to call the named service with the transport
+func New(serviceName string, transport ITransport) IClient {
+	return client{
+		serviceName: serviceName,
+		transport:   transport,
+	}
+}
+
+type client struct {
+	serviceName string
+	transport   ITransport
+	consumer    *msvc.Consumer
+	maxDur      time.Duration
+}
+
+func (c client) WithConsumer(consumer msvc.Consumer) IClient {
+	c.consumer = &consumer
+	return c
+}
+
+func (c client) WithMaxDuration(maxDur time.Duration) IClient {
+	c.maxDur = maxDur
+	return c
+}
+
+func (c client) Call(ctx context.Context, operName string, request interface{}, response interface{}) error {
+	_, err := c.CallMessage(ctx, operName, request, response)
+	return err
+}
+
+//responseMessage decodes the response data later into the caller's type
+type responseMessage struct {
+	Header   *msvc.ResponseHeader `json:"header,omitempty"`
+	Request  interface{}          `json:"request,omitempty"`
+	Error    *msvc.Error          `json:"error,omitempty"`
+	Response json.RawMessage      `json:"response,omitempty"`
+}
+
+func (c client) CallMessage(ctx context.Context, operName string, request interface{}, response interface{}) (msvc.ResponseMessage, error) {
+	header, err := c.header(ctx, time.Now())
+	if err != nil {
+		return msvc.ResponseMessage{}, err
+	}
+	requestMessage := msvc.RequestMessage{}
+	requestMessage.Header = header
+	requestMessage.Request = request
+	jsonRequestMessage, err := json.Marshal(requestMessage)
+	if err != nil {
+		return msvc.ResponseMessage{}, log.Wrapf(err, "Failed to encode request message for %s.%s", c.serviceName, operName)
+	}
+
+	jsonResponseMessage, err := c.transport.Send(ctx, c.serviceName, operName, jsonRequestMessage)
+	if err != nil {
+		return msvc.ResponseMessage{}, log.Wrapf(err, "Failed to send request %s to %s.%s", requestMessage.Header.UUID, c.serviceName, operName)
+	}
+
+	var rm responseMessage
+	if err := json.Unmarshal(jsonResponseMessage, &rm); err != nil {
+		return msvc.ResponseMessage{}, log.Wrapf(err, "Failed to decode response message from %s.%s", c.serviceName, operName)
+	}
+	result := msvc.ResponseMessage{
+		Header:  rm.Header,
+		Request: rm.Request,
+		Error:   rm.Error,
+	}
+	if rm.Error != nil {
+		return result, rm.Error
+	}
+	if response != nil && len(rm.Response) > 0 {
+		if err := json.Unmarshal(rm.Response, response); err != nil {
+			return result, log.Wrapf(err, "Failed to decode %s.%s response into %T", c.serviceName, operName, response)
+		}
+		result.Response = response
+	}
+	return result, nil
+} //client.CallMessage()
+
+//header returns the request header with a new UUID, the consumer and
+//the max-duration that is left before the ctx deadline,
+//or context.DeadlineExceeded when no time is left
+func (c client) header(ctx context.Context, now time.Time) (*msvc.RequestHeader, error) {
+	h := &msvc.RequestHeader{}
+	h.Timestamp = now.Format(msvc.TimestampFormat)
+	h.UUID = msvc.NewUUID()
+	h.Consumer = c.consumer
+	if h.Consumer == nil {
+		if service := msvc.ServiceFromContext(ctx); service != nil {
+			h.Consumer = &msvc.Consumer{Name: service.Name()}
+		}
+	}
+	h.MaxDur = c.maxDur
+	if deadline, ok := ctx.Deadline(); ok {
+		h.MaxDur = deadline.Sub(now)
+		if h.MaxDur <= 0 {
+			//do not send max-duration 0 or less, as the service does not apply it
+			return nil, context.DeadlineExceeded
+		}
+	}
+	return h, nil
+} //client.header()
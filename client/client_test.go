@@ -0,0 +1,28 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jansemmelink/msvc/client"
+)
+
+//failTransport fails the test when a request is sent
+type failTransport struct {
+	t *testing.T
+}
+
+func (ft failTransport) Send(ctx context.Context, serviceName string, operName string, jsonRequestMessage []byte) ([]byte, error) {
+	ft.t.Fatalf("Expected %s.%s not to be sent", serviceName, operName)
+	return nil, nil
+}
+
+func TestCallExpiredContext(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	c := client.New("test", failTransport{t: t})
+	if err := c.Call(ctx, "hello", nil, nil); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v but got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,22 @@
+//Package loopback implements a client.IClient that sends requests in memory
+//to a micro-service in the same process that is attached to msvc/server/loopback
+package loopback
+
+import (
+	"context"
+
+	"github.com/jansemmelink/msvc/client"
+	"github.com/jansemmelink/msvc/server/loopback"
+)
+
+//New creates a client to call the named service in this process
+func New(serviceName string) client.IClient {
+	return client.New(serviceName, transport{})
+}
+
+//transport implements client.ITransport
+type transport struct{}
+
+func (t transport) Send(ctx context.Context, serviceName string, operName string, jsonRequestMessage []byte) ([]byte, error) {
+	return loopback.Send(ctx, serviceName, operName, jsonRequestMessage)
+}
@@ -0,0 +1,65 @@
+package loopback_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+	"github.com/jansemmelink/msvc/client/loopback"
+	server "github.com/jansemmelink/msvc/server/loopback"
+)
+
+//echoOper responds with the value in the request
+type echoOper struct {
+	msvc.Oper
+	Value string `json:"value" validate:"required"`
+}
+
+func (o echoOper) Results() []msvc.IResult { return nil }
+
+func (o echoOper) Run() (interface{}, *msvc.Error) {
+	return o.Value, nil
+}
+
+func TestLoopback(t *testing.T) {
+	detach := server.Attach(msvc.New("test").WithOper("echo", echoOper{}))
+	defer detach()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		service      string
+		request      interface{}
+		wantResponse string
+		wantType     string //msvc.Error type, "" for success
+		wantErr      bool   //another error
+	}{
+		{name: "success", ctx: context.Background(), service: "test", request: map[string]string{"value": "x"}, wantResponse: "x"},
+		{name: "oper error", ctx: context.Background(), service: "test", request: map[string]string{}, wantType: "invalidRequest"},
+		{name: "not attached", ctx: context.Background(), service: "other", request: map[string]string{"value": "x"}, wantErr: true},
+		{name: "cancelled", ctx: cancelled, service: "test", request: map[string]string{"value": "x"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var response string
+			err := loopback.New(test.service).Call(test.ctx, "echo", test.request, &response)
+			e, isOperError := err.(*msvc.Error)
+			switch {
+			case test.wantType != "":
+				if !isOperError || e.Type != test.wantType {
+					t.Fatalf("Expected error type %s but got %v", test.wantType, err)
+				}
+			case test.wantErr:
+				if err == nil || isOperError {
+					t.Fatalf("Expected a transport error but got %v", err)
+				}
+			case err != nil:
+				t.Fatalf("Expected success but got %v", err)
+			case response != test.wantResponse:
+				t.Fatalf("Expected response \"%s\" but got \"%s\"", test.wantResponse, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+//Package nats implements a client.IClient that sends requests with NATS request-reply
+//to the subject <service>.<oper> that the msvc/server/nats server subscribes to
+package nats
+
+import (
+	"context"
+	"time"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc/client"
+	"github.com/nats-io/nats.go"
+)
+
+//defaultTimeout is used when ctx has no deadline
+const defaultTimeout = 10 * time.Second
+
+//New creates a client to call the named service over the connection.
+//The caller remains responsible to close the connection
+func New(conn *nats.Conn, serviceName string) client.IClient {
+	return client.New(serviceName, transport{conn: conn})
+}
+
+//transport implements client.ITransport
+type transport struct {
+	conn *nats.Conn
+}
+
+func (t transport) Send(ctx context.Context, serviceName string, operName string, jsonRequestMessage []byte) ([]byte, error) {
+	timeout := defaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	subject := serviceName + "." + operName
+	msg, err := t.conn.Request(subject, jsonRequestMessage, timeout)
+	if err != nil {
+		return nil, log.Wrapf(err, "NATS request to %s failed", subject)
+	}
+	return msg.Data, nil
+}
@@ -0,0 +1,52 @@
+//Package rest implements a client.IClient that sends requests with HTTP POST
+//to <url>/<service>/<oper> as served by the msvc/server/rest server
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc/client"
+)
+
+//New creates a client to call the named service at the URL, e.g. "http://localhost:12345"
+func New(url string, serviceName string) client.IClient {
+	return client.New(serviceName, transport{
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: http.DefaultClient,
+	})
+}
+
+//transport implements client.ITransport
+type transport struct {
+	url        string
+	httpClient *http.Client
+}
+
+//Send returns the response message also when the HTTP status is not OK,
+//because the error is described in the response message
+func (t transport) Send(ctx context.Context, serviceName string, operName string, jsonRequestMessage []byte) ([]byte, error) {
+	url := t.url + "/" + serviceName + "/" + operName
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(jsonRequestMessage))
+	if err != nil {
+		return nil, log.Wrapf(err, "Failed to create HTTP request to %s", url)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := t.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, log.Wrapf(err, "HTTP POST %s failed", url)
+	}
+	defer res.Body.Close()
+	jsonResponseMessage, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, log.Wrapf(err, "Failed to read HTTP response from %s", url)
+	}
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		return nil, log.Wrapf(nil, "HTTP POST %s -> %s without JSON response", url, res.Status)
+	}
+	return jsonResponseMessage, nil
+}
@@ -0,0 +1,166 @@
+package msvc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jansemmelink/log"
+)
+
+//ConcurrencyLimit limits the number of requests that run at the same time.
+//Requests beyond MaxConcurrent wait in a queue of up to MaxQueued requests
+//for at most QueueTimeout, else they fail with Error.Type "overloaded",
+//or with "timeout" when the request expires while it waits in the queue
+type ConcurrencyLimit struct {
+	//MaxConcurrent is the number of requests that may run at the same time (0 for no limit)
+	MaxConcurrent int
+	//MaxQueued is the number of requests that may wait for a slot (0 rejects immediately)
+	MaxQueued int
+	//QueueTimeout is the max time a request waits in the queue (0 waits until the request expires)
+	QueueTimeout time.Duration
+}
+
+//allOpersLimitName is the oper label used in metrics for the limit on all operations
+const allOpersLimitName = "_all"
+
+//WithConcurrencyLimit limits the requests running at the same time over all operations
+func (msvc msvc) WithConcurrencyLimit(limit ConcurrencyLimit) IMicroService {
+	msvc.settings.limiter = newLimiter(limit)
+	return msvc
+}
+
+//WithOperConcurrencyLimit limits the requests running at the same time for one operation.
+//It applies in addition to the limit set with WithConcurrencyLimit()
+func (msvc msvc) WithOperConcurrencyLimit(operName string, limit ConcurrencyLimit) IMicroService {
+	if _, ok := msvc.operTmpl[operName]; !ok {
+		panic(log.Wrapf(nil, "MicroService[%s].oper[%s] does not exist", msvc.name, operName))
+	}
+	msvc.operLimiters[operName] = newLimiter(limit)
+	return msvc
+}
+
+//Concurrency returns the number of requests running and waiting in the queue
+//for the named operation, or for all operations when operName is ""
+func (msvc msvc) Concurrency(operName string) (inFlight int, queued int) {
+	l := msvc.settings.limiter
+	if operName != "" {
+		l = msvc.operLimiters[operName]
+	}
+	if l == nil {
+		return 0, 0
+	}
+	return l.counts()
+}
+
+//acquire waits for a slot in the oper and the global limits
+//and returns the func to call when the request completed.
+//The oper slot is taken first, so requests queued for a busy oper
+//do not hold global slots that other opers need
+func (msvc msvc) acquire(ctx context.Context, operName string) (func(), error) {
+	operLimiter := msvc.operLimiters[operName]
+	if err := operLimiter.acquire(ctx); err != nil {
+		return nil, log.Wrapf(err, "MicroService[%s].oper[%s] overloaded", msvc.name, operName)
+	}
+	if err := msvc.settings.limiter.acquire(ctx); err != nil {
+		operLimiter.release()
+		return nil, log.Wrapf(err, "MicroService[%s] overloaded", msvc.name)
+	}
+	return func() {
+		operLimiter.release()
+		msvc.settings.limiter.release()
+	}, nil
+}
+
+//limitNames returns the oper labels of all limits to write in metrics
+func (msvc msvc) limitNames() []string {
+	names := make([]string, 0, len(msvc.operLimiters)+1)
+	if msvc.settings.limiter != nil {
+		names = append(names, allOpersLimitName)
+	}
+	for operName := range msvc.operLimiters {
+		names = append(names, operName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//limiterCounts returns the counts of the limit named in metrics
+func (msvc msvc) limiterCounts(limitName string) (int, int) {
+	if limitName == allOpersLimitName {
+		return msvc.Concurrency("")
+	}
+	return msvc.Concurrency(limitName)
+}
+
+//limiter is a semaphore with a bounded wait queue.
+//A nil limiter does not limit anything
+type limiter struct {
+	limit  ConcurrencyLimit
+	slots  chan struct{}
+	mutex  sync.Mutex
+	queued int
+}
+
+func newLimiter(limit ConcurrencyLimit) *limiter {
+	if limit.MaxConcurrent <= 0 {
+		return nil
+	}
+	return &limiter{
+		limit: limit,
+		slots: make(chan struct{}, limit.MaxConcurrent),
+	}
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	//wait in the queue if there is space
+	l.mutex.Lock()
+	if l.queued >= l.limit.MaxQueued {
+		l.mutex.Unlock()
+		return log.Wrapf(nil, "%d requests in progress and %d queued", l.limit.MaxConcurrent, l.limit.MaxQueued)
+	}
+	l.queued++
+	l.mutex.Unlock()
+	defer func() {
+		l.mutex.Lock()
+		l.queued--
+		l.mutex.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if l.limit.QueueTimeout > 0 {
+		timer := time.NewTimer(l.limit.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return log.Wrapf(nil, "queued for %v", l.limit.QueueTimeout)
+	case <-ctx.Done():
+		return log.Wrapf(ctx.Err(), "request expired in the queue")
+	}
+} //limiter.acquire()
+
+func (l *limiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+func (l *limiter) counts() (inFlight int, queued int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.slots), l.queued
+}
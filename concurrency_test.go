@@ -0,0 +1,75 @@
+package msvc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jansemmelink/msvc"
+)
+
+//blockOper runs until the test closes the injected release channel
+type blockOper struct {
+	msvc.Oper
+	Name    string        `json:"name"`
+	Release chan struct{} `json:"-" inject:"release"`
+}
+
+func (o blockOper) Results() []msvc.IResult { return nil }
+
+func (o blockOper) Run() (interface{}, *msvc.Error) {
+	<-o.Release
+	return "done", nil
+}
+
+//waitFor polls until the condition is true and fails the test if it takes too long
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timeout waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBusyOperDoesNotStarveOthers(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithOper("busy", blockOper{}).
+		WithOper("other", blockOper{}).
+		WithConcurrencyLimit(msvc.ConcurrencyLimit{MaxConcurrent: 2}).
+		WithOperConcurrencyLimit("busy", msvc.ConcurrencyLimit{MaxConcurrent: 1, MaxQueued: 5})
+
+	//one busy request runs and the others wait in the queue of the busy oper
+	done := make(chan msvc.ResponseMessage, 4)
+	for i := 0; i < 3; i++ {
+		go func() {
+			done <- svc.HandleJSON("busy", []byte(`{}`))
+		}()
+	}
+	waitFor(t, "queued busy requests", func() bool {
+		inFlight, queued := svc.Concurrency("busy")
+		return inFlight == 1 && queued == 2
+	})
+	if inFlight, _ := svc.Concurrency(""); inFlight != 1 {
+		t.Fatalf("Expected queued requests not to hold global slots, got %d in flight", inFlight)
+	}
+
+	//the other oper still gets a global slot
+	go func() {
+		done <- svc.HandleJSON("other", []byte(`{}`))
+	}()
+	waitFor(t, "other request to run", func() bool {
+		inFlight, _ := svc.Concurrency("")
+		return inFlight == 2
+	})
+
+	close(release)
+	for i := 0; i < 4; i++ {
+		if response := <-done; response.Error != nil {
+			t.Fatalf("Expected success but got %+v", response.Error)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package msvc
+
+import (
+	"context"
+	"time"
+)
+
+//IOperWithContext may be implemented by an operation in addition to IOper.
+//When implemented, HandleJSON calls RunContext() instead of Run() with a context
+//that expires when the request header's timestamp + max-duration is reached,
+//is cancelled when the micro-service shuts down and carries the request meta data
+//and logger (see LoggerFromContext)
+type IOperWithContext interface {
+	RunContext(ctx context.Context) (interface{}, *Error)
+}
+
+//contextKey is used to store request meta data in the context
+type contextKey int
+
+const (
+	contextKeyOperName contextKey = iota
+	contextKeyHeader
+	contextKeyLogger
+	contextKeyService
+	contextKeyConfig
+	contextKeySlots
+)
+
+//requestContext creates the context for one request to the named operation
+//derived from the micro-service context so it is cancelled when the service shuts down.
+//When the request specified a max-duration, the context deadline is set to timestamp + max-duration
+func requestContext(parent context.Context, service IMicroService, operName string, header *RequestHeader, logger ILogger, timestamp time.Time, maxDur time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.WithValue(parent, contextKeyService, service)
+	ctx = context.WithValue(ctx, contextKeyOperName, operName)
+	ctx = context.WithValue(ctx, contextKeyLogger, logger)
+	if header != nil {
+		ctx = context.WithValue(ctx, contextKeyHeader, header)
+	}
+	if maxDur > 0 {
+		return context.WithDeadline(ctx, timestamp.Add(maxDur))
+	}
+	return context.WithCancel(ctx)
+} //requestContext()
+
+//ServiceFromContext returns the micro-service handling the request
+//or nil if the context was not created for a request
+func ServiceFromContext(ctx context.Context) IMicroService {
+	if service, ok := ctx.Value(contextKeyService).(IMicroService); ok {
+		return service
+	}
+	return nil
+}
+
+//OperNameFromContext returns the name of the operation being served in this context
+//or "" if the context was not created for a request
+func OperNameFromContext(ctx context.Context) string {
+	if operName, ok := ctx.Value(contextKeyOperName).(string); ok {
+		return operName
+	}
+	return ""
+}
+
+//HeaderFromContext returns the request header or nil if the request did not have a header
+func HeaderFromContext(ctx context.Context) *RequestHeader {
+	if header, ok := ctx.Value(contextKeyHeader).(*RequestHeader); ok {
+		return header
+	}
+	return nil
+}
+
+//UUIDFromContext returns the request header UUID or "" if not specified
+func UUIDFromContext(ctx context.Context) string {
+	if header := HeaderFromContext(ctx); header != nil {
+		return header.UUID
+	}
+	return ""
+}
+
+//ConsumerFromContext returns the request header consumer or nil if not specified
+func ConsumerFromContext(ctx context.Context) *Consumer {
+	if header := HeaderFromContext(ctx); header != nil {
+		return header.Consumer
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package msvc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+//describeOperName is the reserved operation that returns the ServiceDescription
+const describeOperName = "_describe"
+
+//ServiceDescription describes all operations in a micro-service
+type ServiceDescription struct {
+	Name    string              `json:"name" doc:"Name of the micro-service."`
+	Opers   []OperDescription   `json:"opers" doc:"Operations in the micro-service, sorted by name."`
+	Results []ResultDescription `json:"results" doc:"Results that the framework may return for any operation."`
+}
+
+//OperDescription describes one operation
+type OperDescription struct {
+	Name    string              `json:"name" doc:"Name of the operation."`
+	Async   bool                `json:"async,omitempty" doc:"True when the operation responds with a Job and runs in the background."`
+	Request []FieldDescription  `json:"request,omitempty" doc:"Fields in the request data."`
+	Results []ResultDescription `json:"results,omitempty" doc:"Results declared by the operation."`
+}
+
+//FieldDescription describes one field in a request
+type FieldDescription struct {
+	Name   string             `json:"name" doc:"JSON name of the field."`
+	Type   string             `json:"type,omitempty" doc:"JSON type: string, number, integer, boolean, object or array. Empty for any type."`
+	GoType string             `json:"go-type" doc:"Go type of the field."`
+	Doc    string             `json:"doc,omitempty" doc:"Text from the doc tag of the field."`
+	Rules  string             `json:"validate,omitempty" doc:"Validation rules from the validate tag of the field."`
+	Fields []FieldDescription `json:"fields,omitempty" doc:"Fields of an object, or of the items in an array or object values in a map."`
+}
+
+//ResultDescription describes one IResult
+type ResultDescription struct {
+	Type        string `json:"type" doc:"Error type."`
+	Description string `json:"description,omitempty" doc:"When this result is returned."`
+	HTTPStatus  int    `json:"http-status,omitempty" doc:"HTTP status code used when returned over HTTP."`
+}
+
+//Describe returns the description of all operations in the micro-service
+//excluding the reserved operations (with names starting with "_")
+func (msvc msvc) Describe() ServiceDescription {
+	sd := ServiceDescription{
+		Name:    msvc.name,
+		Opers:   []OperDescription{},
+		Results: describeResults(frameworkResults),
+	}
+	for _, operName := range msvc.operNames() {
+		sd.Opers = append(sd.Opers, OperDescription{
+			Name:    operName,
+			Async:   msvc.asyncOpers[operName],
+			Request: describeFields(reflect.TypeOf(msvc.operTmpl[operName]), nil),
+			Results: describeResults(msvc.operResults[operName]),
+		})
+	}
+	return sd
+} //msvc.Describe()
+
+//operNames returns the sorted names of all operations that are not reserved
+func (msvc msvc) operNames() []string {
+	operNames := make([]string, 0, len(msvc.operTmpl))
+	for operName := range msvc.operTmpl {
+		if !isReservedOperName(operName) {
+			operNames = append(operNames, operName)
+		}
+	}
+	sort.Strings(operNames)
+	return operNames
+}
+
+//isReservedOperName is true for names of operations provided by the framework
+func isReservedOperName(operName string) bool {
+	return strings.HasPrefix(operName, "_")
+}
+
+func describeResults(results []IResult) []ResultDescription {
+	rds := make([]ResultDescription, 0, len(results))
+	for _, r := range results {
+		rds = append(rds, ResultDescription{
+			Type:        r.Type(),
+			Description: r.Description(),
+			HTTPStatus:  r.HTTPStatus(),
+		})
+	}
+	return rds
+}
+
+//describeFields returns the JSON fields of a struct type, or of the items of an array or map type
+//visited contains the struct types being described to stop recursion in recursive types
+func describeFields(t reflect.Type, visited []reflect.Type) []FieldDescription {
+	t = elemType(t)
+	if t.Kind() != reflect.Struct || jsonType(t) != "object" {
+		return nil
+	}
+	for _, v := range visited {
+		if v == t {
+			return nil
+		}
+	}
+	visited = append(visited, t)
+
+	fds := []FieldDescription{}
+	for _, f := range jsonFields(t) {
+		fds = append(fds, FieldDescription{
+			Name:   f.name,
+			Type:   jsonType(f.field.Type),
+			GoType: f.field.Type.String(),
+			Doc:    f.field.Tag.Get("doc"),
+			Rules:  f.field.Tag.Get("validate"),
+			Fields: describeFields(f.field.Type, visited),
+		})
+	}
+	return fds
+} //describeFields()
+
+//jsonField is a struct field that is encoded in JSON
+type jsonField struct {
+	name      string
+	omitEmpty bool
+	field     reflect.StructField
+}
+
+//jsonFields returns the fields of the struct type that are encoded in JSON
+//with the fields of embedded structs in place as encoding/json does
+func jsonFields(t reflect.Type) []jsonField {
+	fields := []jsonField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if _, isResource := f.Tag.Lookup("inject"); tag == "-" || isResource {
+			continue
+		}
+		tagName := strings.Split(tag, ",")[0]
+		if f.Anonymous && len(tagName) == 0 {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				//index of embedded fields starts with the index of the embedded struct
+				for _, ef := range jsonFields(ft) {
+					ef.field.Index = append([]int{i}, ef.field.Index...)
+					fields = append(fields, ef)
+				}
+				continue
+			}
+		}
+		if len(f.PkgPath) > 0 {
+			continue //unexported
+		}
+		name := tagName
+		if len(name) == 0 {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{
+			name:      name,
+			omitEmpty: strings.Contains(tag, ",omitempty"),
+			field:     f,
+		})
+	}
+	return fields
+} //jsonFields()
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+//elemType returns the type of values in pointers, arrays, slices and maps
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Array, reflect.Slice, reflect.Map:
+			if t.Kind() != reflect.Ptr && jsonType(t) == "string" {
+				return t //e.g. []byte
+			}
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+//jsonType returns the JSON type used to encode values of a Go type
+//or "" if the type is not known, e.g. for interface{} or custom marshalers
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "string"
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string" //[]byte is base64 encoded
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return ""
+} //jsonType()
+
+//describeOper is the reserved operation that returns the ServiceDescription
+type describeOper struct {
+	Oper
+}
+
+func (describeOper) Results() []IResult { return nil }
+func (describeOper) Validate() error    { return nil }
+
+func (o describeOper) Run() (interface{}, *Error) {
+	return ServiceFromContext(o.Context()).Describe(), nil
+}
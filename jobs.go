@@ -0,0 +1,392 @@
+package msvc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jansemmelink/log"
+)
+
+//reserved operations to manage the jobs of asynchronous operations
+const (
+	statusOperName = "_status"
+	resultOperName = "_result"
+	cancelOperName = "_cancel"
+)
+
+//Job statuses
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+//defaultJobRetention is how long ended jobs are kept unless WithJobRetention() is used
+const defaultJobRetention = 24 * time.Hour
+
+//jobPurgeInterval is how often Serve() purges ended jobs
+const jobPurgeInterval = time.Minute
+
+//Job is the state of one request to an asynchronous operation (see WithAsyncOper()).
+//It is the response when the job is started and from the _status, _result and _cancel operations
+type Job struct {
+	ID       string      `json:"id" doc:"Job ID to use in _status, _result and _cancel requests."`
+	Oper     string      `json:"oper" doc:"Name of the asynchronous operation."`
+	UUID     string      `json:"uuid" doc:"UUID of the request that started the job."`
+	Instance string      `json:"instance" doc:"Instance of the micro-service that runs the job."`
+	Consumer *Consumer   `json:"consumer,omitempty" doc:"Consumer that started the job."`
+	Status   string      `json:"status" doc:"running, completed, failed or cancelled."`
+	Started  time.Time   `json:"started" doc:"Time when the job started."`
+	Ended    *time.Time  `json:"ended,omitempty" doc:"Time when the job ended."`
+	Response interface{} `json:"response,omitempty" doc:"Response of the operation when completed, only returned by _result."`
+	Error    *Error      `json:"error,omitempty" doc:"Error returned by the operation when failed."`
+}
+
+//IJobStore keeps the state of jobs. The default store keeps jobs in memory,
+//use WithJobStore() to keep them elsewhere, e.g. in files with jobs/file
+type IJobStore interface {
+	//Save creates or replaces the job
+	Save(job Job) error
+	//Load returns the job or nil if not found
+	Load(id string) (*Job, error)
+	//Purge deletes jobs that ended before the specified time
+	Purge(endedBefore time.Time) error
+	//Running returns all jobs with status running
+	Running() ([]Job, error)
+}
+
+//WithAsyncOper adds an operation that runs in the background. The request is validated
+//before the response is returned with a Job, then the consumer polls the job with
+//_status, gets the response with _result or stops the job with _cancel
+func (msvc msvc) WithAsyncOper(name string, oper IOper) IMicroService {
+	m := msvc.WithOper(name, oper)
+	msvc.asyncOpers[name] = true
+	return m
+}
+
+//WithJobStore replaces the default in-memory store of jobs
+func (msvc msvc) WithJobStore(store IJobStore) IMicroService {
+	msvc.jobs.store = store
+	return msvc
+}
+
+//WithJobRetention sets how long ended jobs are kept
+func (msvc msvc) WithJobRetention(retention time.Duration) IMicroService {
+	msvc.jobs.retention = retention
+	return msvc
+}
+
+//Job returns the named job or nil if not found
+func (msvc msvc) Job(id string) (*Job, error) {
+	return msvc.jobs.store.Load(id)
+}
+
+//CancelJob cancels the context of a running job and returns the job or nil if not found.
+//The job is marked cancelled even when the oper ignores its context and continues to run
+func (msvc msvc) CancelJob(id string) (*Job, error) {
+	msvc.jobs.mutex.Lock()
+	defer msvc.jobs.mutex.Unlock()
+	job, err := msvc.jobs.store.Load(id)
+	if err != nil || job == nil {
+		return job, err
+	}
+	cancel, ok := msvc.jobs.cancel[id]
+	if !ok {
+		//already ended or running in another instance
+		return job, nil
+	}
+	cancel()
+	delete(msvc.jobs.cancel, id)
+	now := time.Now()
+	job.Status = JobCancelled
+	job.Ended = &now
+	if err := msvc.jobs.store.Save(*job); err != nil {
+		return nil, log.Wrapf(err, "Failed to save cancelled job %s", id)
+	}
+	return job, nil
+} //msvc.CancelJob()
+
+type jobs struct {
+	store     IJobStore
+	retention time.Duration
+	mutex     sync.Mutex
+	cancel    map[string]context.CancelFunc
+}
+
+func newJobs() *jobs {
+	return &jobs{
+		store:     newMemoryJobStore(),
+		retention: defaultJobRetention,
+		cancel:    make(map[string]context.CancelFunc),
+	}
+}
+
+//purge jobs that ended longer than the retention ago
+func (j *jobs) purge(now time.Time) {
+	if err := j.store.Purge(now.Add(-j.retention)); err != nil {
+		log.Errorf("Failed to purge jobs: %+v", err)
+	}
+}
+
+//abortStale marks the jobs that are running in the store but not in this process as failed,
+//because the process that ran them terminated. The instance of the job is not compared,
+//as a restarted container often gets the same hostname and pid. It is called when the
+//service starts, so a store must not be shared by instances of the service that run
+//at the same time
+func (j *jobs) abortStale() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	running, err := j.store.Running()
+	if err != nil {
+		log.Errorf("Failed to load running jobs: %+v", err)
+		return
+	}
+	for _, job := range running {
+		if _, ok := j.cancel[job.ID]; !ok {
+			j.fail(job, "Job aborted because instance "+job.Instance+" terminated")
+		}
+	}
+}
+
+//abort cancels the jobs still running in this instance when the service terminates
+//and marks them as failed. Their results are discarded if they still complete
+func (j *jobs) abort() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for id, cancel := range j.cancel {
+		cancel()
+		delete(j.cancel, id)
+		job, err := j.store.Load(id)
+		if err != nil || job == nil {
+			log.Errorf("Cannot abort job %s: %+v", id, err)
+			continue
+		}
+		j.fail(*job, "Job aborted because the micro-service terminated")
+	}
+}
+
+//fail saves the job as failed with a terminating error
+func (j *jobs) fail(job Job, description string) {
+	now := time.Now()
+	job.Status = JobFailed
+	job.Ended = &now
+	job.Error = &Error{
+		Type:        "terminating",
+		Description: description,
+	}
+	if err := j.store.Save(job); err != nil {
+		log.Errorf("Failed to save aborted job %s: %+v", job.ID, err)
+	}
+}
+
+//startJob validates the request then runs the oper in the background and returns the new job.
+//The job context is not cancelled when the request completes, only when the job is cancelled,
+//the operation timeout expires or the micro-service shuts down
+func (msvc msvc) startJob(ctx context.Context, request Request) ResponseMessage {
+	if errorMessage := msvc.validateOper(ctx, request); errorMessage != nil {
+		return *errorMessage
+	}
+
+	now := time.Now()
+	job := Job{
+		ID:       NewUUID(),
+		Oper:     request.OperName,
+		UUID:     request.Header.UUID,
+		Instance: msvc.instance,
+		Consumer: request.Header.Consumer,
+		Status:   JobRunning,
+		Started:  now,
+	}
+	logger := LoggerFromContext(ctx)
+	jobCtx, cancel := requestContext(msvc.ctx, msvc, request.OperName, request.Header, logger, now, msvc.operTimeouts[request.OperName])
+	jobCtx = context.WithValue(jobCtx, contextKeyConfig, ConfigFromContext(ctx))
+	setOperContext(reflect.ValueOf(request.Oper), jobCtx)
+
+	msvc.jobs.mutex.Lock()
+	defer msvc.jobs.mutex.Unlock()
+	if err := msvc.jobs.store.Save(job); err != nil {
+		cancel()
+		logger.Errorf("MicroService[%s].oper[%s] failed to save job: %+v", msvc.name, request.OperName, err)
+		return ResponseMessage{
+			Error: &Error{
+				Type:        "internalError",
+				Description: log.Wrapf(err, "Failed to save job").Error(),
+			},
+		}
+	}
+	msvc.jobs.cancel[job.ID] = cancel
+	logger.Debugf("Started job %s", job.ID)
+
+	//the job keeps the request slots until it ended, so it counts against the
+	//concurrency limits and Serve() drains it before the service terminates
+	release := slotsFromContext(ctx).take()
+	go func(job Job) {
+		var response ResponseMessage
+		defer release()
+		defer func() {
+			if r := recover(); r != nil {
+				response = msvc.recovered(job.Oper, job.UUID, r)
+			}
+			msvc.endJob(jobCtx, job, response)
+		}()
+		response = msvc.execOper(jobCtx, request)
+	}(job)
+	return ResponseMessage{Response: job}
+} //msvc.startJob()
+
+//endJob saves the result of the job unless it was cancelled
+func (msvc msvc) endJob(jobCtx context.Context, job Job, response ResponseMessage) {
+	msvc.jobs.mutex.Lock()
+	defer msvc.jobs.mutex.Unlock()
+	cancel, ok := msvc.jobs.cancel[job.ID]
+	if !ok {
+		log.Debugf("Job %s ended after it was cancelled, discarded result", job.ID)
+		return
+	}
+	delete(msvc.jobs.cancel, job.ID)
+	defer cancel()
+
+	now := time.Now()
+	job.Ended = &now
+	switch {
+	case jobCtx.Err() == context.DeadlineExceeded:
+		job.Status = JobFailed
+		job.Error = &Error{
+			Type:        "timeout",
+			Description: "Job did not complete within the operation timeout",
+		}
+	case jobCtx.Err() != nil:
+		job.Status = JobCancelled
+	case response.Error != nil:
+		job.Status = JobFailed
+		job.Error = response.Error
+	default:
+		job.Status = JobCompleted
+		job.Response = response.Response
+	}
+	if err := msvc.jobs.store.Save(job); err != nil {
+		log.Errorf("Failed to save job %s: %+v", job.ID, err)
+	}
+} //msvc.endJob()
+
+//memoryJobStore is the default IJobStore
+type memoryJobStore struct {
+	mutex sync.Mutex
+	jobs  map[string]Job
+}
+
+func newMemoryJobStore() *memoryJobStore {
+	return &memoryJobStore{
+		jobs: make(map[string]Job),
+	}
+}
+
+func (s *memoryJobStore) Save(job Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *memoryJobStore) Load(id string) (*Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (s *memoryJobStore) Running() ([]Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	running := []Job{}
+	for _, job := range s.jobs {
+		if job.Status == JobRunning {
+			running = append(running, job)
+		}
+	}
+	return running, nil
+}
+
+func (s *memoryJobStore) Purge(endedBefore time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, job := range s.jobs {
+		if job.Ended != nil && job.Ended.Before(endedBefore) {
+			delete(s.jobs, id)
+		}
+	}
+	return nil
+}
+
+//jobResults are the results of the job operations
+var jobResults = []IResult{
+	Result("unknownJob", http.StatusNotFound, "The job does not exist or was purged."),
+	Result("jobRunning", http.StatusConflict, "The job has not ended yet."),
+}
+
+//jobRequest is the request data of the job operations
+type jobRequest struct {
+	Oper
+	ID string `json:"id" doc:"ID of the job." validate:"required"`
+}
+
+func (jobRequest) Results() []IResult    { return jobResults }
+func (jobRequest) Response() interface{} { return Job{} }
+
+//job loads the requested job or returns an error if not found
+func (o jobRequest) job(load func(id string) (*Job, error)) (*Job, *Error) {
+	job, err := load(o.ID)
+	if err != nil {
+		o.Log().Errorf("Failed to load job %s: %+v", o.ID, err)
+		return nil, &Error{Type: "internalError", Description: log.Wrapf(err, "Failed to load job").Error()}
+	}
+	if job == nil {
+		return nil, &Error{Type: "unknownJob", Description: "Job " + o.ID + " not found"}
+	}
+	return job, nil
+}
+
+//statusOper returns the job without the response
+type statusOper struct{ jobRequest }
+
+func (o statusOper) Run() (interface{}, *Error) {
+	job, e := o.job(ServiceFromContext(o.Context()).Job)
+	if e != nil {
+		return nil, e
+	}
+	job.Response = nil
+	return job, nil
+}
+
+//resultOper returns the ended job with the response
+type resultOper struct{ jobRequest }
+
+func (o resultOper) Run() (interface{}, *Error) {
+	job, e := o.job(ServiceFromContext(o.Context()).Job)
+	if e != nil {
+		return nil, e
+	}
+	if job.Status == JobRunning {
+		return nil, &Error{Type: "jobRunning", Description: "Job " + o.ID + " is still running"}
+	}
+	return job, nil
+}
+
+//cancelOper cancels the job if still running
+type cancelOper struct{ jobRequest }
+
+func (o cancelOper) Run() (interface{}, *Error) {
+	job, e := o.job(ServiceFromContext(o.Context()).CancelJob)
+	if e != nil {
+		return nil, e
+	}
+	job.Response = nil
+	return job, nil
+}
@@ -0,0 +1,131 @@
+//Package file implements a msvc.IJobStore that keeps each job in a JSON file
+//named <dir>/<id>.json, so jobs survive restarts. When the service starts,
+//jobs still running in the previous process are marked failed, so the directory
+//must not be shared by instances that run at the same time, e.g.:
+//
+//	store, err := file.New("./jobs")
+//	...
+//	svc.WithJobStore(store)
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+)
+
+//fileStore implements msvc.IJobStore with one file per job
+type fileStore struct {
+	dir string
+}
+
+//New creates the directory if necessary and returns the store
+func New(dir string) (msvc.IJobStore, error) {
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return nil, log.Wrapf(err, "Failed to create job directory %s", dir)
+	}
+	return fileStore{dir: dir}, nil
+}
+
+//fileName returns the name of the job file or "" if the id cannot be a file name
+func (fs fileStore) fileName(id string) string {
+	if len(id) == 0 || strings.ContainsAny(id, "/\\.") {
+		return ""
+	}
+	return filepath.Join(fs.dir, id+".json")
+}
+
+//Save writes the job to a temporary file then renames it
+//so that Load never reads a partially written job
+func (fs fileStore) Save(job msvc.Job) error {
+	fileName := fs.fileName(job.ID)
+	if fileName == "" {
+		return log.Wrapf(nil, "Invalid job id \"%s\"", job.ID)
+	}
+	jsonJob, err := json.Marshal(job)
+	if err != nil {
+		return log.Wrapf(err, "Failed to encode job %s", job.ID)
+	}
+	if err := ioutil.WriteFile(fileName+".tmp", jsonJob, 0660); err != nil {
+		return log.Wrapf(err, "Failed to write job file %s", fileName)
+	}
+	if err := os.Rename(fileName+".tmp", fileName); err != nil {
+		return log.Wrapf(err, "Failed to rename job file %s", fileName)
+	}
+	return nil
+}
+
+func (fs fileStore) Load(id string) (*msvc.Job, error) {
+	fileName := fs.fileName(id)
+	if fileName == "" {
+		return nil, nil
+	}
+	jsonJob, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, log.Wrapf(err, "Failed to read job file %s", fileName)
+	}
+	job := &msvc.Job{}
+	if err := json.Unmarshal(jsonJob, job); err != nil {
+		return nil, log.Wrapf(err, "Failed to decode job file %s", fileName)
+	}
+	return job, nil
+}
+
+//Running returns all jobs with status running
+func (fs fileStore) Running() ([]msvc.Job, error) {
+	files, err := ioutil.ReadDir(fs.dir)
+	if err != nil {
+		return nil, log.Wrapf(err, "Failed to read job directory %s", fs.dir)
+	}
+	running := []msvc.Job{}
+	for _, file := range files {
+		id := strings.TrimSuffix(file.Name(), ".json")
+		if id == file.Name() {
+			continue
+		}
+		job, err := fs.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		if job != nil && job.Status == msvc.JobRunning {
+			running = append(running, *job)
+		}
+	}
+	return running, nil
+}
+
+//Purge deletes the files of jobs that ended before the specified time.
+//Files not modified since then are the only candidates, as jobs are written when they end
+func (fs fileStore) Purge(endedBefore time.Time) error {
+	files, err := ioutil.ReadDir(fs.dir)
+	if err != nil {
+		return log.Wrapf(err, "Failed to read job directory %s", fs.dir)
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(file.Name(), ".json")
+		if id == file.Name() || !file.ModTime().Before(endedBefore) {
+			continue
+		}
+		job, err := fs.Load(id)
+		if err != nil {
+			log.Errorf("Cannot purge job %s: %+v", id, err)
+			continue
+		}
+		if job == nil || job.Ended == nil || !job.Ended.Before(endedBefore) {
+			continue
+		}
+		if err := os.Remove(fs.fileName(id)); err != nil {
+			return log.Wrapf(err, "Failed to delete job file %s", fs.fileName(id))
+		}
+	}
+	return nil
+} //fileStore.Purge()
@@ -0,0 +1,50 @@
+package msvc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+)
+
+func jobFromResponse(t *testing.T, response msvc.ResponseMessage) msvc.Job {
+	t.Helper()
+	if response.Error != nil {
+		t.Fatalf("Expected a job but got %+v", response.Error)
+	}
+	jsonJob, _ := json.Marshal(response.Response)
+	var job msvc.Job
+	if err := json.Unmarshal(jsonJob, &job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestJobKeepsSlotUntilEnded(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithAsyncOper("block", blockOper{}).
+		WithOperConcurrencyLimit("block", msvc.ConcurrencyLimit{MaxConcurrent: 1})
+
+	job := jobFromResponse(t, svc.HandleJSON("block", []byte(`{}`)))
+	if job.Status != msvc.JobRunning {
+		t.Fatalf("Expected running job but got %+v", job)
+	}
+	if response := svc.HandleJSON("block", []byte(`{}`)); response.Error == nil || response.Error.Type != "overloaded" {
+		t.Fatalf("Expected overloaded while the job runs but got %+v", response.Error)
+	}
+	if response := svc.HandleJSON("_result", []byte(`{"request":{"id":"`+job.ID+`"}}`)); response.Error == nil || response.Error.Type != "jobRunning" {
+		t.Fatalf("Expected jobRunning but got %+v", response.Error)
+	}
+
+	close(release)
+	waitFor(t, "the slot to be released", func() bool {
+		inFlight, _ := svc.Concurrency("block")
+		return inFlight == 0
+	})
+	job = jobFromResponse(t, svc.HandleJSON("_result", []byte(`{"request":{"id":"`+job.ID+`"}}`)))
+	if job.Status != msvc.JobCompleted || job.Response != "done" {
+		t.Fatalf("Expected completed job but got %+v", job)
+	}
+}
@@ -0,0 +1,24 @@
+package msvc
+
+import "testing"
+
+func TestAbortStaleJobsOfSameInstance(t *testing.T) {
+	j := newJobs()
+
+	//a restarted container often has the same hostname and pid as before
+	if err := j.store.Save(Job{ID: "stale", Status: JobRunning, Instance: newInstanceID()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.store.Save(Job{ID: "running", Status: JobRunning, Instance: newInstanceID()}); err != nil {
+		t.Fatal(err)
+	}
+	j.cancel["running"] = func() {}
+
+	j.abortStale()
+	if job, _ := j.store.Load("stale"); job == nil || job.Status != JobFailed {
+		t.Fatalf("Expected the stale job to be failed, got %+v", job)
+	}
+	if job, _ := j.store.Load("running"); job == nil || job.Status != JobRunning {
+		t.Fatalf("Expected the job running in this process to keep running, got %+v", job)
+	}
+}
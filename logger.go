@@ -0,0 +1,51 @@
+package msvc
+
+import (
+	"context"
+
+	"github.com/jansemmelink/log"
+)
+
+//ILogger logs on behalf of one request, so that all lines logged for
+//the request can be found by grepping for its UUID
+type ILogger interface {
+	Debugf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+//NewLogger returns a logger that writes the UUID at the start of every line
+func NewLogger(uuid string) ILogger {
+	return requestLogger{uuid: uuid}
+}
+
+type requestLogger struct {
+	uuid string
+}
+
+func (l requestLogger) Debugf(format string, args ...interface{}) {
+	log.Debugf("[%s] "+format, append([]interface{}{l.uuid}, args...)...)
+}
+
+func (l requestLogger) Errorf(format string, args ...interface{}) {
+	log.Errorf("[%s] "+format, append([]interface{}{l.uuid}, args...)...)
+}
+
+//defaultLogger is used when there is no request
+type defaultLogger struct{}
+
+func (defaultLogger) Debugf(format string, args ...interface{}) {
+	log.Debugf(format, args...)
+}
+
+func (defaultLogger) Errorf(format string, args ...interface{}) {
+	log.Errorf(format, args...)
+}
+
+//LoggerFromContext returns the request logger or a logger without UUID
+//if the context was not created for a request
+func LoggerFromContext(ctx context.Context) ILogger {
+	if logger, ok := ctx.Value(contextKeyLogger).(ILogger); ok {
+		return logger
+	}
+	return defaultLogger{}
+}
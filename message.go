@@ -12,7 +12,6 @@ import (
 func fromJSON(output interface{}, jsonMessage []byte) error {
 	decoder := json.NewDecoder(strings.NewReader(string(jsonMessage)))
 	decoder.UseNumber()
-	log.Debugf("Decoding into output %T ...", output)
 	err := decoder.Decode(&output)
 	if err != nil {
 		return err
@@ -20,6 +19,9 @@ func fromJSON(output interface{}, jsonMessage []byte) error {
 	return nil
 } //fromJSON()
 
+//TimestampFormat is used to write the timestamp in message headers
+const TimestampFormat = "2006-01-02 15:04:05.000-07:00"
+
 //Header is common in all messages, but optional :-)
 type Header struct {
 	Timestamp string    `json:"timestamp" doc:"Timestamp when this message is sent written as ..."`
@@ -71,10 +73,10 @@ func (requestMessage RequestMessage) Validate(operName string) (time.Time, time.
 	// 	"2006-01-02 15:04:05.000",
 	// 	message.Header.Timestamp,
 	// 	time.Local)
-	timestamp, err := time.Parse("2006-01-02 15:04:05.000+07:00", h.Timestamp)
+	timestamp, err := time.Parse(TimestampFormat, h.Timestamp)
 	if err != nil {
 		//try without milliseconds
-		timestamp, err = time.Parse("2006-01-02 15:04:05+07:00", h.Timestamp)
+		timestamp, err = time.Parse("2006-01-02 15:04:05-07:00", h.Timestamp)
 		if err != nil {
 			//try local time with milliseconds
 			timestamp, err = time.ParseInLocation("2006-01-02 15:04:05.00", h.Timestamp, time.Local)
@@ -82,13 +84,14 @@ func (requestMessage RequestMessage) Validate(operName string) (time.Time, time.
 				//try local time without milliseconds
 				timestamp, err = time.ParseInLocation("2006-01-02 15:04:05", h.Timestamp, time.Local)
 				if err != nil {
-					return time.Now(), 0, log.Wrapf(nil, "Invalid timestamp. Expecting %s", time.Now().Format("2006-01-02 15:04:05.000+07:00"))
+					return time.Now(), 0, log.Wrapf(nil, "Invalid timestamp. Expecting %s", time.Now().Format(TimestampFormat))
 				}
 			}
 		}
 	}
 
 	//h.UUID and h.Consumer needs no validation - echo whatever we got in the response
+	//(if UUID is not specified, the micro-service assigns one)
 
 	//h.Provider in request indicates who 'should' handle this, but we already know the operation name
 	//so we do not validate it yet...
@@ -105,7 +108,7 @@ func (requestMessage RequestMessage) Validate(operName string) (time.Time, time.
 
 //ResponseMessage ...
 type ResponseMessage struct {
-	Header  *ResponseHeader `json:"header,omitempty"`
+	Header  *ResponseHeader `json:"header,omitempty" doc:"Header echoes the request uuid and consumer, identifies the provider and contains the duration."`
 	Request interface{}     `json:"request,omitempty" doc:"Request data is only present here if specified echo-request:true in the request message."`
 
 	//followed by either error or response:
@@ -132,10 +135,25 @@ type Provider struct {
 	Name string `json:"name,omitempty" doc:"Name to identify the provider"`
 	TID  string `json:"tid,omitempty" doc:"Optional Transaction ID, significant only in the context of the provider."`
 	SID  string `json:"sid,omitempty" doc:"Optional Session ID, significant only in the context of the provider."`
+
+	Instance string `json:"instance,omitempty" doc:"In response, identifies the instance of the service that handled the request."`
 }
 
 //Error ...
 type Error struct {
 	Type        string `json:"type,omitempty" doc:"Type of error is a name to identify the error in a lookup table."`
 	Description string `json:"description,omitempty" doc:"Free format text to further explain the error."`
+	Stack       string `json:"stack,omitempty" doc:"Stack trace of an internalError, only present when the service runs in debug mode."`
+
+	Fields []FieldError `json:"fields,omitempty" doc:"List of all invalid fields when the request data is not valid."`
+
+	RetryAfter time.Duration `json:"retry-after,omitempty" doc:"Minimum duration to wait before retrying when the request was rate limited."`
+}
+
+//Error implements the error interface so clients can return *Error as a Go error
+func (e *Error) Error() string {
+	if len(e.Description) == 0 {
+		return e.Type
+	}
+	return e.Type + ": " + e.Description
 }
@@ -0,0 +1,187 @@
+package msvc
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+//durationBuckets are the upper bounds in seconds of the request duration histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+//unknownOperName is used in metrics for requests to operations that do not exist,
+//so that consumers cannot create any number of metrics with random names
+const unknownOperName = "_unknown"
+
+//metrics records the requests handled by each operation
+type metrics struct {
+	mutex sync.Mutex
+	opers map[string]*operMetrics
+}
+
+type operMetrics struct {
+	requests      uint64
+	inProgress    int64
+	errors        map[string]uint64
+	bucketCounts  []uint64
+	durationSum   float64
+	durationCount uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		opers: make(map[string]*operMetrics),
+	}
+}
+
+//oper returns the metrics of the named operation, creating it if necessary
+//the caller must hold the mutex
+func (m *metrics) oper(operName string) *operMetrics {
+	om, ok := m.opers[operName]
+	if !ok {
+		om = &operMetrics{
+			errors:       make(map[string]uint64),
+			bucketCounts: make([]uint64, len(durationBuckets)),
+		}
+		m.opers[operName] = om
+	}
+	return om
+}
+
+//begin is called when a request to the named operation is received
+func (m *metrics) begin(operName string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	om := m.oper(operName)
+	om.requests++
+	om.inProgress++
+}
+
+//end is called when the request to the named operation completed
+func (m *metrics) end(operName string, dur time.Duration, err *Error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	om := m.oper(operName)
+	om.inProgress--
+	if err != nil {
+		om.errors[err.Type]++
+	}
+	seconds := dur.Seconds()
+	for i, le := range durationBuckets {
+		if seconds <= le {
+			om.bucketCounts[i]++
+		}
+	}
+	om.durationSum += seconds
+	om.durationCount++
+} //metrics.end()
+
+//metricsOperName returns the operation name to use in metrics
+func (msvc msvc) metricsOperName(operName string) string {
+	if _, ok := msvc.operTmpl[operName]; ok {
+		return operName
+	}
+	return unknownOperName
+}
+
+//WriteMetrics writes the metrics of all operations in the Prometheus text exposition format
+func (msvc msvc) WriteMetrics(w io.Writer) error {
+	m := msvc.metrics
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	operNames := make([]string, 0, len(m.opers))
+	for operName := range m.opers {
+		operNames = append(operNames, operName)
+	}
+	sort.Strings(operNames)
+
+	pw := &promWriter{w: w}
+	pw.header("msvc_requests_total", "counter", "Number of requests received per operation.")
+	for _, operName := range operNames {
+		pw.sample("msvc_requests_total", msvc.labels(operName), float64(m.opers[operName].requests))
+	}
+
+	pw.header("msvc_requests_in_progress", "gauge", "Number of requests in progress per operation.")
+	for _, operName := range operNames {
+		pw.sample("msvc_requests_in_progress", msvc.labels(operName), float64(m.opers[operName].inProgress))
+	}
+
+	pw.header("msvc_errors_total", "counter", "Number of requests that failed per operation and error type.")
+	for _, operName := range operNames {
+		om := m.opers[operName]
+		errorTypes := make([]string, 0, len(om.errors))
+		for errorType := range om.errors {
+			errorTypes = append(errorTypes, errorType)
+		}
+		sort.Strings(errorTypes)
+		for _, errorType := range errorTypes {
+			pw.sample("msvc_errors_total", msvc.labels(operName, "type", errorType), float64(om.errors[errorType]))
+		}
+	}
+
+	pw.header("msvc_request_duration_seconds", "histogram", "Duration of requests per operation.")
+	for _, operName := range operNames {
+		om := m.opers[operName]
+		for i, le := range durationBuckets {
+			pw.sample("msvc_request_duration_seconds_bucket", msvc.labels(operName, "le", fmt.Sprintf("%g", le)), float64(om.bucketCounts[i]))
+		}
+		pw.sample("msvc_request_duration_seconds_bucket", msvc.labels(operName, "le", "+Inf"), float64(om.durationCount))
+		pw.sample("msvc_request_duration_seconds_sum", msvc.labels(operName), om.durationSum)
+		pw.sample("msvc_request_duration_seconds_count", msvc.labels(operName), float64(om.durationCount))
+	}
+
+	pw.header("msvc_panics_total", "counter", "Number of panics recovered per operation.")
+	for _, operName := range operNames {
+		pw.sample("msvc_panics_total", msvc.labels(operName), float64(msvc.panics.get(operName)))
+	}
+
+	pw.header("msvc_late_results_total", "counter", "Number of results discarded per operation because they completed after a timeout.")
+	for _, operName := range operNames {
+		pw.sample("msvc_late_results_total", msvc.labels(operName), float64(msvc.lateResults.get(operName)))
+	}
+
+	limitNames := msvc.limitNames()
+	pw.header("msvc_concurrency_in_flight", "gauge", "Number of requests holding a slot per concurrency limit.")
+	for _, limitName := range limitNames {
+		inFlight, _ := msvc.limiterCounts(limitName)
+		pw.sample("msvc_concurrency_in_flight", msvc.labels(limitName), float64(inFlight))
+	}
+	pw.header("msvc_concurrency_queued", "gauge", "Number of requests waiting for a slot per concurrency limit.")
+	for _, limitName := range limitNames {
+		_, queued := msvc.limiterCounts(limitName)
+		pw.sample("msvc_concurrency_queued", msvc.labels(limitName), float64(queued))
+	}
+	return pw.err
+} //msvc.WriteMetrics()
+
+//labels returns the service and oper labels followed by the optional name/value pairs
+func (msvc msvc) labels(operName string, nameValues ...string) string {
+	labels := fmt.Sprintf("service=%q,oper=%q", msvc.name, operName)
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		labels += fmt.Sprintf(",%s=%q", nameValues[i], nameValues[i+1])
+	}
+	return labels
+}
+
+//promWriter writes the Prometheus text exposition format and remembers the first error
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *promWriter) header(name, metricType, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func (pw *promWriter) sample(name, labels string, value float64) {
+	pw.printf("%s{%s} %g\n", name, labels, value)
+}
+
+func (pw *promWriter) printf(format string, args ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, format, args...)
+	}
+}
@@ -0,0 +1,53 @@
+package msvc
+
+import (
+	"context"
+
+	"github.com/jansemmelink/log"
+)
+
+//Request is a decoded request that is passed through the middleware to the oper
+type Request struct {
+	OperName string
+	Header   *RequestHeader
+	Oper     IOper
+}
+
+//Handler processes a decoded request and returns the response message
+type Handler func(ctx context.Context, request Request) ResponseMessage
+
+//Middleware is an interceptor around the handler of a request.
+//It is called with the next handler in the chain and returns a handler that may
+//inspect the request, call next with the same or another context, inspect the
+//response returned by next, or short-circuit by returning a response without calling next.
+//Next must be called before the handler returns, i.e. not in a goroutine that
+//outlives it, because the concurrency slot of the request is released when it returns.
+type Middleware func(next Handler) Handler
+
+//WithMiddleware adds middleware for all operations.
+//Middleware added first is called first, and all operations' middleware is called
+//before middleware added with WithOperMiddleware()
+func (msvc msvc) WithMiddleware(middleware ...Middleware) IMicroService {
+	msvc.settings.middleware = append(append([]Middleware{}, msvc.settings.middleware...), middleware...)
+	return msvc
+}
+
+//WithOperMiddleware adds middleware only for the named operation
+func (msvc msvc) WithOperMiddleware(operName string, middleware ...Middleware) IMicroService {
+	if _, ok := msvc.operTmpl[operName]; !ok {
+		panic(log.Wrapf(nil, "MicroService[%s].oper[%s] does not exist", msvc.name, operName))
+	}
+	msvc.operMiddleware[operName] = append(msvc.operMiddleware[operName], middleware...)
+	return msvc
+}
+
+//handler returns the chain of middleware for the named operation
+//ending in the handler that runs the oper
+func (msvc msvc) handler(operName string) Handler {
+	middleware := append(append([]Middleware{}, msvc.settings.middleware...), msvc.operMiddleware[operName]...)
+	h := Handler(msvc.run)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+} //msvc.handler()
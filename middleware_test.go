@@ -0,0 +1,44 @@
+package msvc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+)
+
+func TestWithoutChaining(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithOper("block", blockOper{})
+
+	//settings apply without using the returned IMicroService
+	called := false
+	svc.WithMiddleware(func(next msvc.Handler) msvc.Handler {
+		return func(ctx context.Context, request msvc.Request) msvc.ResponseMessage {
+			called = true
+			return next(ctx, request)
+		}
+	})
+	svc.WithConcurrencyLimit(msvc.ConcurrencyLimit{MaxConcurrent: 1})
+
+	done := make(chan msvc.ResponseMessage)
+	go func() {
+		done <- svc.HandleJSON("block", []byte(`{}`))
+	}()
+	waitFor(t, "the request to run", func() bool {
+		inFlight, _ := svc.Concurrency("")
+		return inFlight == 1
+	})
+	if response := svc.HandleJSON("block", []byte(`{}`)); response.Error == nil || response.Error.Type != "overloaded" {
+		t.Fatalf("Expected overloaded but got %+v", response.Error)
+	}
+	close(release)
+	if response := <-done; response.Error != nil {
+		t.Fatalf("Expected success but got %+v", response.Error)
+	}
+	if !called {
+		t.Fatalf("Expected the middleware to be called")
+	}
+}
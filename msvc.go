@@ -1,9 +1,17 @@
 package msvc
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"reflect"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/jansemmelink/config"
 	"github.com/jansemmelink/log"
@@ -13,27 +21,121 @@ import (
 type IMicroService interface {
 	Name() string
 	WithOper(name string, operTmpl IOper) IMicroService
+	WithDebug(debug bool) IMicroService
+	WithDrainTimeout(drainTimeout time.Duration) IMicroService
+	WithConfigWatch(interval time.Duration) IMicroService
+	WithMiddleware(middleware ...Middleware) IMicroService
+	WithResource(name string, resource interface{}) IMicroService
+	Resource(name string) interface{}
+	LoadConfig() error
+	WithOperMiddleware(operName string, middleware ...Middleware) IMicroService
+	WithConcurrencyLimit(limit ConcurrencyLimit) IMicroService
+	WithRateLimit(consumerName string, operName string, limit RateLimit) IMicroService
+	WithOperConcurrencyLimit(operName string, limit ConcurrencyLimit) IMicroService
+	Concurrency(operName string) (inFlight int, queued int)
 	Serve()
 	HandleJSON(operName string, jsonRequestMessage []byte) ResponseMessage
-	//
-	Test(operName string, requestJSON string)
+	HandleBatchJSON(jsonBatchRequest []byte) BatchResponse
+	WriteMetrics(w io.Writer) error
+	Results(operName string) []IResult
+	Describe() ServiceDescription
+	RequestSchema(operName string) *Schema
+	ResponseSchema(operName string) *Schema
+	WriteSchemas(dir string) error
+	OpenAPI(serverURLs ...string) OpenAPIDocument
+	WriteOpenAPI(fileName string, serverURLs ...string) error
+	Result(operName string, resultType string) IResult
+	Panics(operName string) int
+	LateResults(operName string) int
+	WithOperTimeout(operName string, timeout time.Duration) IMicroService
+	WithAsyncOper(name string, oper IOper) IMicroService
+	WithJobStore(store IJobStore) IMicroService
+	WithJobRetention(retention time.Duration) IMicroService
+	Job(id string) (*Job, error)
+	CancelJob(id string) (*Job, error)
+	Test(operName string, requestJSON string, response interface{}) (ResponseMessage, error)
 }
 
 //New creates the named micro-service
 func New(name string) IMicroService {
-	return msvc{
-		name: name,
+	//context is cancelled when the service shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	m := msvc{
+		name:     name,
+		instance: newInstanceID(),
+		ctx:      ctx,
+		cancel:   cancel,
 		//default config from files in ./conf/...json|yml|properties
-		configSet: config.NewSet().MustSource("files", "./conf"),
+		configSet: newConfigSet(),
 		//operations is empty until WithOper() is used
-		operTmpl: make(map[string]IOper),
+		operTmpl:    make(map[string]IOper),
+		operResults: make(map[string][]IResult),
+		//middleware is empty until WithMiddleware() or WithOperMiddleware() is used
+		operMiddleware: make(map[string][]Middleware),
+		operLimiters:   make(map[string]*limiter),
+		rateLimiter:    newRateLimiter(),
+		resources:      make(map[string]interface{}),
+		operConfigs:    newOperConfigs(),
+		panics:         newOperCounter(),
+		lateResults:    newOperCounter(),
+		operTimeouts:   make(map[string]time.Duration),
+		asyncOpers:     make(map[string]bool),
+		jobs:           newJobs(),
+		metrics:        newMetrics(),
+		auditor:        newAuditor(),
+		//requests in progress are drained when terminating
+		lifecycle: &lifecycle{},
+		settings: &settings{
+			drainTimeout: defaultDrainTimeout,
+		},
 	}
+
+	//reserved operations provided by the framework
+	m.operTmpl[describeOperName] = describeOper{}
+	for operName, oper := range map[string]IOper{
+		statusOperName: statusOper{},
+		resultOperName: resultOper{},
+		cancelOperName: cancelOper{},
+	} {
+		m.operTmpl[operName] = oper
+		m.operResults[operName] = oper.Results()
+	}
+	return m
 }
 
 type msvc struct {
 	name      string
+	instance  string
+	ctx       context.Context
+	cancel    context.CancelFunc
 	configSet config.ISet
 	operTmpl  map[string]IOper
+	//operResults is the catalogue of results for each operation
+	operResults    map[string][]IResult
+	operMiddleware map[string][]Middleware
+	operLimiters   map[string]*limiter
+	rateLimiter    *rateLimiter
+	resources      map[string]interface{}
+	operConfigs    *operConfigs
+	panics         *operCounter
+	lateResults    *operCounter
+	operTimeouts   map[string]time.Duration
+	asyncOpers     map[string]bool
+	jobs           *jobs
+	metrics        *metrics
+	auditor        *auditor
+	lifecycle      *lifecycle
+	settings       *settings
+}
+
+//settings are changed with the With...() methods and kept behind a pointer like the maps,
+//so that a With...() call applies to the service whether or not the caller uses the returned value
+type settings struct {
+	middleware   []Middleware
+	limiter      *limiter
+	debug        bool
+	drainTimeout time.Duration
+	configWatch  time.Duration
 }
 
 func (msvc msvc) Name() string {
@@ -44,67 +146,231 @@ func (msvc msvc) WithOper(name string, operTmpl IOper) IMicroService {
 	if len(name) == 0 {
 		panic("cannot add oper without a name")
 	}
+	if isReservedOperName(name) {
+		panic(log.Wrapf(nil, "MicroService[%s].oper[%s] name is reserved", msvc.name, name))
+	}
 	if _, ok := msvc.operTmpl[name]; ok {
 		panic(log.Wrapf(nil, "MicroService[%s].oper[%s] already exists", msvc.name, name))
 	}
+
+	//check the validate tags in the request struct
+	if err := checkValidateTags(reflect.TypeOf(operTmpl), nil); err != nil {
+		panic(log.Wrapf(err, "MicroService[%s].oper[%s] cannot be validated", msvc.name, name))
+	}
+	if err := checkInjectTags(reflect.TypeOf(operTmpl)); err != nil {
+		panic(log.Wrapf(err, "MicroService[%s].oper[%s] cannot be injected", msvc.name, name))
+	}
+
+	//read the results catalogue
+	results := operTmpl.Results()
+	checkResults(msvc.name, name, results)
 	msvc.operTmpl[name] = operTmpl
+	msvc.operResults[name] = results
 	return msvc
 }
 
-func (msvc msvc) Test(operName string, requestJSON string) {
-	operTmpl, ok := msvc.operTmpl[operName]
-	if !ok {
-		panic(log.Wrapf(nil, "MicroService[%s].oper[%s] does not exist", msvc.name, operName))
-	}
+//Results returns the catalogue of results declared by the named operation,
+//excluding the FrameworkResults() that any operation may return
+func (msvc msvc) Results(operName string) []IResult {
+	return append([]IResult{}, msvc.operResults[operName]...)
+}
 
-	//create new oper instance
-	operValue := reflect.New(reflect.TypeOf(operTmpl))
-	operStructPtr := operValue.Interface()
-	if err := json.Unmarshal([]byte(requestJSON), operStructPtr); err != nil {
-		panic(log.Wrapf(err, "Failed to decode request into %T", operStructPtr))
+//Result returns the named result as declared by the operation or the framework
+//or nil if not declared
+func (msvc msvc) Result(operName string, resultType string) IResult {
+	if r := findResult(msvc.operResults[operName], resultType); r != nil {
+		return r
 	}
+	return findResult(frameworkResults, resultType)
+}
 
-	oper := operStructPtr.(IOper)
-	if err := oper.Validate(); err != nil {
-		panic(log.Wrapf(err, "Invalid request"))
-	}
+//WithDebug controls if panic details and stack traces are returned to the consumer
+func (msvc msvc) WithDebug(debug bool) IMicroService {
+	msvc.settings.debug = debug
+	return msvc
+}
 
-	log.Debugf("Request is valid")
-	result, response := oper.Run()
+//WithDrainTimeout sets how long Serve() waits for requests in progress to complete
+//when the service is terminating, before their context is cancelled
+func (msvc msvc) WithDrainTimeout(drainTimeout time.Duration) IMicroService {
+	msvc.settings.drainTimeout = drainTimeout
+	return msvc
+}
 
-	log.Debugf("MicroService[%s].Oper[%s].Run() -> result=%v, response=(%T)%+v", msvc.name, operName, result, response, response)
-	return
+//Panics returns the nr of panics recovered in the named operation
+func (msvc msvc) Panics(operName string) int {
+	return msvc.panics.get(operName)
 }
 
+//Test runs the request data (JSON, or "" for none) through the same pipeline as
+//HandleJSON() in a request message without a header, and decodes the response data
+//into response, which must be a pointer or nil to ignore response data.
+//It returns the response message and, if the operation failed, the *Error.
+//Opers that implement IOperWithConfig need LoadConfig() to be called first.
+func (msvc msvc) Test(operName string, requestJSON string, response interface{}) (ResponseMessage, error) {
+	jsonRequestMessage := `{}`
+	if len(requestJSON) > 0 {
+		jsonRequestMessage = `{"request":` + requestJSON + `}`
+	}
+	responseMessage := msvc.HandleJSON(operName, []byte(jsonRequestMessage))
+	if responseMessage.Error != nil {
+		return responseMessage, responseMessage.Error
+	}
+	if response != nil && responseMessage.Response != nil {
+		//encode and decode as it would be sent to a consumer
+		jsonResponse, err := json.Marshal(responseMessage.Response)
+		if err != nil {
+			return responseMessage, log.Wrapf(err, "Failed to encode %s response", operName)
+		}
+		if err := json.Unmarshal(jsonResponse, response); err != nil {
+			return responseMessage, log.Wrapf(err, "Failed to decode %s response into %T", operName, response)
+		}
+	}
+	return responseMessage, nil
+} //msvc.Test()
+
 //Serve the micro-service on all the configured server interfaces
+//until the process is interrupted or terminated, or until all servers terminated.
+//The configuration is reloaded on SIGHUP and when changed (see WithConfigWatch())
 func (msvc msvc) Serve() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	lastModified := configModified()
+
+	//fail before serving if configuration is not valid or resources cannot be injected
+	if err := msvc.LoadConfig(); err != nil {
+		panic(err)
+	}
+	if err := msvc.checkInjection(); err != nil {
+		panic(err)
+	}
+
+	//jobs still running in a previous process will never complete
+	msvc.jobs.abortStale()
+
+	//open the configured audit sinks
+	msvc.auditor.open(msvc.configSet, msvc.name)
+	defer msvc.auditor.close()
+
 	//start all the configured servers
+	serversCtx, stopServers := context.WithCancel(context.Background())
+	defer stopServers()
 	wg := sync.WaitGroup{}
-	startConfiguredServers(&wg, msvc.configSet, msvc)
+	runningServers := newServers(serversCtx, &wg, msvc)
+	runningServers.update(configuredServers(msvc.configSet))
+	serversDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(serversDone)
+	}()
+
+	//optionally check for configuration changes
+	var watch <-chan time.Time
+	if msvc.settings.configWatch > 0 {
+		ticker := time.NewTicker(msvc.settings.configWatch)
+		defer ticker.Stop()
+		watch = ticker.C
+	}
+
+	purgeTicker := time.NewTicker(jobPurgeInterval)
+	defer purgeTicker.Stop()
+
+	//wait for a terminate signal or for all servers to terminate
+	//and reload the configuration when asked to or when it changed
+	reload := func() {
+		if err := msvc.reload(runningServers); err != nil {
+			log.Errorf("%+v", err)
+		}
+	}
+waitLoop:
+	for {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				lastModified = configModified()
+				reload()
+				continue
+			}
+			log.Debugf("MicroService[%s] received %v, terminating...", msvc.name, sig)
+			break waitLoop
+		case now := <-purgeTicker.C:
+			msvc.jobs.purge(now)
+		case <-watch:
+			if modified := configModified(); modified != lastModified {
+				lastModified = modified
+				reload()
+			}
+		case <-serversDone:
+			log.Debugf("MicroService[%s] all servers terminated", msvc.name)
+			break waitLoop
+		}
+	}
 
-	//wait for all servers to terminate
-	wg.Wait()
+	//reject new requests and wait for requests and jobs in progress to complete
+	msvc.lifecycle.terminate()
+	if !msvc.lifecycle.drain(msvc.settings.drainTimeout) {
+		log.Errorf("MicroService[%s] requests still in progress after %v", msvc.name, msvc.settings.drainTimeout)
+	}
+
+	//cancel the context of any requests still running and fail the jobs that did not complete
+	msvc.cancel()
+	msvc.jobs.abort()
+
+	//stop the servers and wait for them to terminate
+	stopServers()
+	<-serversDone
 	log.Debugf("All servers terminated.")
-}
+} //msvc.Serve()
 
 //HandleJSON is called by all the IServer implementations when they received a JSON message
-func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) ResponseMessage {
-	operTmpl, ok := msvc.operTmpl[operName]
-	if !ok {
-		return ResponseMessage{
-			Header:   nil,
-			Request:  nil,
-			Error:    &Error{Type: "unknownOper"},
-			Response: nil}
-	}
+func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) (responseMessage ResponseMessage) {
+	startTime := time.Now()
 
-	//startTime := time.Now()
-	// monitor.GaugeInc("concurrent_transactions", "")
-	// defer monitor.GaugeDec("concurrent_transactions", "")
+	//write the audit record after the response is complete
+	var requestMessage RequestMessage
+	var uuid string
+	var auditData interface{}
+	defer func() {
+		msvc.audit(operName, startTime, requestMessage, responseMessage, auditData)
+	}()
+
+	//complete the response envelope on all return paths
+	requestTimestamp := startTime
+	defer func() {
+		responseMessage = msvc.responseMessage(requestMessage, uuid, requestTimestamp, responseMessage)
+	}()
+
+	//record metrics for all requests, including those that failed
+	metricsOperName := msvc.metricsOperName(operName)
+	msvc.metrics.begin(metricsOperName)
+	defer func() {
+		msvc.metrics.end(metricsOperName, time.Since(startTime), responseMessage.Error)
+	}()
+
+	//recover from panics in the oper or the framework
+	//so that only this request fails and not the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			responseMessage = msvc.recovered(operName, uuid, r)
+		}
+	}()
 
 	//decode only {"header":{...}}, ignoring the rest of the request message
-	var requestMessage RequestMessage
-	if err := fromJSON(requestMessage.RequestMessageOnlyHeader, jsonRequestMessage); err != nil {
+	err := fromJSON(&requestMessage.RequestMessageOnlyHeader, jsonRequestMessage)
+
+	//include request.header.uuid in all subsequent logging
+	//if absent, assign own unique id
+	if requestMessage.Header != nil && len(requestMessage.Header.UUID) > 0 {
+		uuid = requestMessage.Header.UUID
+	} else {
+		uuid = NewUUID()
+	}
+	logger := NewLogger(uuid)
+	logger.Debugf("MicroService[%s].oper[%s] received %d bytes", msvc.name, operName, len(jsonRequestMessage))
+
+	if err != nil {
+		logger.Debugf("Failed to decode request header: %+v", err)
 		return ResponseMessage{
 			Error: &Error{
 				Type:        "decodeJSONRequestHeader",
@@ -113,10 +379,11 @@ func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) Response
 		}
 	}
 
-	log.Debugf(".Header: %+v", requestMessage)
+	logger.Debugf(".Header: %+v", requestMessage.Header)
 
-	if /*requestTimestamp*/ _ /*maxDur*/, _, err := requestMessage.Validate(operName); err != nil {
-		log.Debugf("Invalid request message")
+	timestamp, maxDur, err := requestMessage.Validate(operName)
+	if err != nil {
+		logger.Debugf("Invalid request message: %+v", err)
 		return ResponseMessage{
 			Error: &Error{
 				Type:        "invalidRequestHeader",
@@ -124,111 +391,89 @@ func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) Response
 			},
 		}
 	}
-	log.Debugf("Valid request message: %+v", requestMessage)
-
-	//reject requests when terminating
-	// if terminating {
-	// 	return "", "", ProcessIsTerminating, errors.Errorf("Process is terminating")
-	// }
-
-	// var result *Result
-	// var resultName string
-	// var msAudit interface{}
-
-	/*
-	 * Log the transaction in prometheus and in the audit log
-	 */
-	// defer func() {
+	requestTimestamp = timestamp
+	logger.Debugf("Valid request message: %+v", requestMessage)
 
-	// 	endTime := time.Now()
-
-	// 	RecordTransaction(
-	// 		domain,
-	// 		operation,
-	// 		message.Header.IntGuid,
-	// 		startTime,
-	// 		endTime,
-	// 		result,
-	// 		resultName,
-	// 		msAudit)
-
-	// }()
+	//store own unique id in the header if the consumer did not specify one
+	if requestMessage.Header == nil {
+		requestMessage.Header = &RequestHeader{}
+	}
+	requestMessage.Header.UUID = uuid
 
-	/*
-	 * Return if request validation failed
-	 */
-	// if err != nil {
+	operTmpl, ok := msvc.operTmpl[operName]
+	if !ok {
+		return ResponseMessage{
+			Error: &Error{Type: "unknownOper"},
+		}
+	}
 
-	// 	err = errors.Wrapf(err,
-	// 		"message validation failed")
-	// 	_log.Errorf("%+v", err)
+	//reject requests above the consumer's rate limit
+	consumerName := ""
+	if requestMessage.Header.Consumer != nil {
+		consumerName = requestMessage.Header.Consumer.Name
+	}
+	if ok, retryAfter := msvc.rateLimiter.allow(consumerName, operName, startTime); !ok {
+		logger.Debugf("Consumer \"%s\" rate limited, retry after %v", consumerName, retryAfter)
+		return ResponseMessage{
+			Error: &Error{
+				Type:        "rateLimited",
+				Description: fmt.Sprintf("Rate limit exceeded, retry after %v", retryAfter),
+				RetryAfter:  retryAfter,
+			},
+		}
+	}
 
-	// 	result = MakeResultFromResultCode(resultCode, err.Error())
-	// 	message.Header.Result = result
+	//context for the oper expires at timestamp + max-duration
+	ctx, cancel := requestContext(msvc.ctx, msvc, operName, requestMessage.Header, logger, timestamp, maxDur)
+	defer cancel()
+	if operTimeout := msvc.operTimeouts[operName]; operTimeout > 0 {
+		//the earliest of the max-duration and the oper timeout applies
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, operTimeout)
+		defer cancelTimeout()
+	}
 
-	// 	ms.connector.Reply(message)
-	// 	return
+	//configuration of the oper at the start of the request
+	ctx = context.WithValue(ctx, contextKeyConfig, msvc.operConfigs.get(operName))
 
-	// } // if message validation failed
+	//slots are released when the request completed, or later when
+	//the oper is still running after a timeout (see requestSlots)
+	slots := &requestSlots{}
+	defer slots.end()
+	ctx = context.WithValue(ctx, contextKeySlots, slots)
 
-	//include request.header.uuid in all subsequent logging
-	//if absent, assign own unique id
-	// _log = _log.With(
-	// 	"guid",
-	// 	message.Header.IntGuid)
-
-	// Catch the panic
-	// defer func() {
-
-	// 	if r := recover(); r != nil {
-	// 		err, ok := r.(error)
-	// 		if ok && err != nil {
-	// 			/*
-	// 				Get stack trace info
-	// 			*/
-	// 			const size = 64 << 10 //64k
-	// 			buf := make([]byte, size)
-	// 			buf = buf[:runtime.Stack(buf, false)]
-	// 			_log.Errorf("System panic with error: %v\n%s", err, buf)
-	// 			message.Header.Result = MakeResult(-1, err.Error(), err.Error())
-	// 		} else {
-	// 			/*
-	// 				Get stack trace info
-	// 			*/
-	// 			const size = 64 << 10 //64k
-	// 			buf := make([]byte, size)
-	// 			buf = buf[:runtime.Stack(buf, false)]
-	// 			_log.Errorf("System panic:\n%s %s", err, buf)
-	// 			message.Header.Result = MakeResult(-1, r.(string), r.(string))
-	// 		}
-
-	// 		json, err := message.ToJSON()
-
-	// 		if err != nil {
-	// 			_log.Errorf("%v", err)
-	// 			return
-	// 		}
-
-	// 		_log.Debugf("Panic Message to send back is %s", json)
-
-	// 		if len(message.Header.ReplyAddress) > 0 {
-	// 			err = ms.connector.Reply(message)
-	// 			if err != nil {
-	// 				_log.Errorf("Failed to reply to %s, error is %v", message.Header.ReplyAddress, err)
-	// 			}
-	// 		}
-	// 	}
-	// }()
+	//reject requests when terminating
+	if !msvc.lifecycle.begin() {
+		return ResponseMessage{
+			Error: &Error{
+				Type:        "terminating",
+				Description: "Micro-service is terminating",
+			},
+		}
+	}
+	slots.add(msvc.lifecycle.end)
+
+	//limit the number of requests running at the same time
+	release, err := msvc.acquire(ctx, operName)
+	if err != nil {
+		logger.Debugf("Rejected: %+v", err)
+		errorType := "overloaded"
+		if ctx.Err() == context.DeadlineExceeded {
+			//max-duration or oper timeout expired while waiting in the queue
+			errorType = "timeout"
+		}
+		return ResponseMessage{
+			Error: &Error{
+				Type:        errorType,
+				Description: err.Error(),
+			},
+		}
+	}
+	slots.add(release)
 
 	//create a new copy of the operation (the request) struct
-	operType := reflect.TypeOf(operTmpl)
-	log.Debugf("operType = %v", operType)
-	var operStructPtrValue reflect.Value
-	if operType.Kind() == reflect.Ptr {
-		operStructPtrValue = reflect.New(operType.Elem())
-	} else {
-		operStructPtrValue = reflect.New(operType)
-	}
+	operStructPtrValue := newOperValue(operTmpl)
+	logger.Debugf("operType = %v", operStructPtrValue.Type())
 
 	//decode the message.request element into the operation
 	requestMessage.Request = operStructPtrValue.Interface()
@@ -240,7 +485,7 @@ func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) Response
 			},
 		}
 	}
-	log.Debugf("Decoded request in message: %+v", requestMessage)
+	logger.Debugf("Decoded request in message: %+v", requestMessage)
 
 	operRequest, ok := requestMessage.Request.(IOper)
 	if !ok {
@@ -251,15 +496,106 @@ func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) Response
 			},
 		}
 	}
-	log.Debugf("Got request: %+v", operRequest)
+	logger.Debugf("Got request: %+v", operRequest)
+
+	//echo a copy of the request, because the oper may still be running
+	//in another goroutine when the response is sent (see msvc.run()),
+	//made before the resources are injected so they are never sent back
+	if requestMessage.Header.EchoRequest {
+		if jsonRequest, err := json.Marshal(operRequest); err == nil {
+			requestMessage.Request = json.RawMessage(jsonRequest)
+		} else {
+			logger.Errorf("Cannot echo request: %+v", err)
+			requestMessage.Request = nil
+		}
+	}
 
-	if err := operRequest.Validate(); err != nil {
-		return operRequest.ErrorMessage("invalidRequest", log.Wrapf(err, "Invalid Request"))
+	//inject shared resources into the new oper
+	if err := msvc.inject(operStructPtrValue); err != nil {
+		logger.Errorf("MicroService[%s].oper[%s] failed to inject resources: %+v", msvc.name, operName, err)
+		return ResponseMessage{
+			Error: &Error{
+				Type:        "internalError",
+				Description: log.Wrapf(err, "Failed to inject resources").Error(),
+			},
+		}
 	}
-	log.Debugf("Valid request: %+v", operRequest)
 
-	operResponse, operError := operRequest.Run()
+	//pass the request through the middleware to run the oper
+	responseMessage = msvc.handler(operName)(ctx, Request{
+		OperName: operName,
+		Header:   requestMessage.Header,
+		Oper:     operRequest,
+	})
+
+	//get optional audit data from the oper, unless it is still running
+	if auditor, ok := operRequest.(IAuditor); ok && !slots.taken() {
+		auditData = auditor.Audit()
+	}
+	return responseMessage
+} //msvc.HandleJSON()
+
+//runOper validates the decoded request and runs the oper (see msvc.run())
+func (msvc msvc) runOper(ctx context.Context, request Request) ResponseMessage {
+	if errorMessage := msvc.validateOper(ctx, request); errorMessage != nil {
+		return *errorMessage
+	}
+	return msvc.execOper(ctx, request)
+}
+
+//validateOper returns the error message when the decoded request is not valid, else nil
+func (msvc msvc) validateOper(ctx context.Context, request Request) *ResponseMessage {
+	operRequest := request.Oper
+
+	//apply validate tags before the oper's own validation
+	if fieldErrors := validateFields(operRequest); len(fieldErrors) > 0 {
+		descriptions := make([]string, 0, len(fieldErrors))
+		for _, fieldError := range fieldErrors {
+			descriptions = append(descriptions, fieldError.Description)
+		}
+		return &ResponseMessage{
+			Error: &Error{
+				Type:        "invalidRequest",
+				Description: "Invalid Request: " + strings.Join(descriptions, "; "),
+				Fields:      fieldErrors,
+			},
+		}
+	}
+	if err := operRequest.Validate(); err != nil {
+		errorMessage := operRequest.ErrorMessage("invalidRequest", log.Wrapf(err, "Invalid Request"))
+		return &errorMessage
+	}
+	if jsonRequest, err := json.Marshal(operRequest); err == nil {
+		//logged as JSON, which excludes the injected resources
+		LoggerFromContext(ctx).Debugf("Valid request: %s", jsonRequest)
+	}
+	return nil
+} //msvc.validateOper()
+
+//execOper runs the validated oper
+func (msvc msvc) execOper(ctx context.Context, request Request) ResponseMessage {
+	operName := request.OperName
+	operRequest := request.Oper
+	logger := LoggerFromContext(ctx)
+
+	var operResponse interface{}
+	var operError *Error
+	if operWithContext, ok := operRequest.(IOperWithContext); ok {
+		operResponse, operError = operWithContext.RunContext(ctx)
+	} else {
+		operResponse, operError = operRequest.Run()
+	}
 	if operError != nil {
+		//only declared results may be returned
+		if msvc.Result(operName, operError.Type) == nil {
+			logger.Errorf("MicroService[%s].oper[%s] returned undeclared result \"%s\": %s", msvc.name, operName, operError.Type, operError.Description)
+			return ResponseMessage{
+				Error: &Error{
+					Type:        "undeclaredResult",
+					Description: fmt.Sprintf("Operation returned undeclared error type \"%s\": %s", operError.Type, operError.Description),
+				},
+			}
+		}
 		return ResponseMessage{
 			Error: operError,
 		}
@@ -268,4 +604,4 @@ func (msvc msvc) HandleJSON(operName string, jsonRequestMessage []byte) Response
 		Error:    nil,
 		Response: operResponse,
 	}
-} //msvc.HandleJSON()
+} //msvc.execOper()
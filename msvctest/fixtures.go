@@ -0,0 +1,229 @@
+package msvctest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+)
+
+//Fixture file names in <dir>/<oper>/<case>/
+const (
+	FixtureRequestFile  = "request.json"
+	FixtureResponseFile = "expected-response.json"
+)
+
+//UpdateFixtures is set with the -update-fixtures flag, e.g. go test -update-fixtures,
+//to make RunFixtures() write the expected responses
+var UpdateFixtures = flag.Bool("update-fixtures", false, "Write the actual responses into the expected-response.json fixture files")
+
+//volatileFields are removed from responses before they are compared,
+//because they are different every time the request is processed
+var volatileFields = [][]string{
+	{"header", "timestamp"},
+	{"header", "uuid"},
+	{"header", "duration"},
+	{"header", "provider", "instance"},
+	{"error", "stack"},
+	{"error", "retry-after"},
+}
+
+//FixtureResult is the outcome of one fixture case
+type FixtureResult struct {
+	Oper    string
+	Case    string
+	Updated bool
+	//Diff shows the lines that differ from the expected response, empty when matched
+	Diff string
+	//Err is set when the fixture could not be read or written
+	Err error
+}
+
+//Failed is true when the fixture case did not pass
+func (fr FixtureResult) Failed() bool {
+	return fr.Err != nil || len(fr.Diff) > 0
+}
+
+//Fixtures runs the fixture cases in dir, each in a directory <dir>/<oper>/<case>/ with:
+//
+//	request.json            the request message passed to HandleJSON() for <oper>
+//	expected-response.json  the expected response message
+//
+//Volatile header fields (timestamp, uuid, duration and provider instance) and
+//the error stack and retry-after are ignored. With update, the expected responses
+//are (re)written from the actual responses instead of compared.
+func Fixtures(svc msvc.IMicroService, dir string, update bool) ([]FixtureResult, error) {
+	operDirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, log.Wrapf(err, "Failed to read fixture directory %s", dir)
+	}
+	results := []FixtureResult{}
+	for _, operDir := range operDirs {
+		if !operDir.IsDir() {
+			continue
+		}
+		caseDirs, err := ioutil.ReadDir(filepath.Join(dir, operDir.Name()))
+		if err != nil {
+			return nil, log.Wrapf(err, "Failed to read fixture directory %s", operDir.Name())
+		}
+		for _, caseDir := range caseDirs {
+			if !caseDir.IsDir() {
+				continue
+			}
+			results = append(results, runFixture(svc, filepath.Join(dir, operDir.Name(), caseDir.Name()), operDir.Name(), caseDir.Name(), update))
+		}
+	}
+	return results, nil
+} //Fixtures()
+
+func runFixture(svc msvc.IMicroService, caseDir string, operName string, caseName string, update bool) FixtureResult {
+	result := FixtureResult{
+		Oper: operName,
+		Case: caseName,
+	}
+	jsonRequestMessage, err := ioutil.ReadFile(filepath.Join(caseDir, FixtureRequestFile))
+	if err != nil {
+		result.Err = log.Wrapf(err, "Failed to read request")
+		return result
+	}
+	actual, err := normalizedJSON(svc.HandleJSON(operName, jsonRequestMessage))
+	if err != nil {
+		result.Err = log.Wrapf(err, "Failed to encode response")
+		return result
+	}
+
+	expectedFile := filepath.Join(caseDir, FixtureResponseFile)
+	if update {
+		if err := ioutil.WriteFile(expectedFile, []byte(actual), 0664); err != nil {
+			result.Err = log.Wrapf(err, "Failed to write %s", expectedFile)
+			return result
+		}
+		result.Updated = true
+		return result
+	}
+
+	jsonExpected, err := ioutil.ReadFile(expectedFile)
+	if err != nil {
+		result.Err = log.Wrapf(err, "Failed to read expected response (use update to create it)")
+		return result
+	}
+	var v interface{}
+	if err := json.Unmarshal(jsonExpected, &v); err != nil {
+		result.Err = log.Wrapf(err, "Failed to decode %s", expectedFile)
+		return result
+	}
+	expected, err := normalizedJSON(v)
+	if err != nil {
+		result.Err = log.Wrapf(err, "Failed to encode expected response")
+		return result
+	}
+	if expected != actual {
+		result.Diff = lineDiff(expected, actual)
+	}
+	return result
+} //runFixture()
+
+//RunFixtures runs the fixtures in dir as sub tests named <oper>/<case>
+//and updates the expected responses when go test is run with -update-fixtures
+func RunFixtures(t *testing.T, svc msvc.IMicroService, dir string) {
+	t.Helper()
+	results, err := Fixtures(svc, dir, *UpdateFixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		result := result
+		t.Run(result.Oper+"/"+result.Case, func(t *testing.T) {
+			switch {
+			case result.Err != nil:
+				t.Fatal(result.Err)
+			case result.Updated:
+				t.Logf("Updated %s", FixtureResponseFile)
+			case len(result.Diff) > 0:
+				t.Fatalf("Response differs from %s (-expected +actual):\n%s", FixtureResponseFile, result.Diff)
+			}
+		})
+	}
+}
+
+//normalizedJSON returns indented JSON of v without the volatile fields
+func normalizedJSON(v interface{}) (string, error) {
+	jsonValue, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(jsonValue)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return "", err
+	}
+	for _, path := range volatileFields {
+		deletePath(generic, path)
+	}
+	indented, err := json.MarshalIndent(generic, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(indented) + "\n", nil
+}
+
+func deletePath(v interface{}, path []string) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+	deletePath(obj[path[0]], path[1:])
+}
+
+//lineDiff returns the lines only in expected prefixed with "-" and
+//the lines only in actual prefixed with "+", with the common lines indented
+func lineDiff(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	//lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := &strings.Builder{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(diff, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(diff, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(diff, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return diff.String()
+} //lineDiff()
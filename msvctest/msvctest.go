@@ -0,0 +1,89 @@
+//Package msvctest has helpers to test operations from go test
+//with the same request pipeline as the servers, e.g.:
+//
+//	func TestHello(t *testing.T) {
+//		svc := Template()
+//		var greeting string
+//		msvctest.MustSucceed(t, svc, "hello", map[string]string{"name": "Jan"}, &greeting)
+//		e := msvctest.MustFail(t, svc, "hello", map[string]string{}, "invalidRequest")
+//		msvctest.AssertFieldError(t, e, "name", "required")
+//	}
+package msvctest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+)
+
+//Call encodes the request data as JSON, unless it is a string, []byte or json.RawMessage
+//with JSON already, and runs it with IMicroService.Test()
+func Call(t testing.TB, svc msvc.IMicroService, operName string, request interface{}, response interface{}) (msvc.ResponseMessage, error) {
+	t.Helper()
+	var requestJSON string
+	switch r := request.(type) {
+	case nil:
+	case string:
+		requestJSON = r
+	case []byte:
+		requestJSON = string(r)
+	case json.RawMessage:
+		requestJSON = string(r)
+	default:
+		jsonRequest, err := json.Marshal(request)
+		if err != nil {
+			t.Fatalf("Failed to encode %s request %+v: %v", operName, request, err)
+		}
+		requestJSON = string(jsonRequest)
+	}
+	return svc.Test(operName, requestJSON, response)
+}
+
+//MustSucceed runs the request and fails the test if the operation failed
+func MustSucceed(t testing.TB, svc msvc.IMicroService, operName string, request interface{}, response interface{}) msvc.ResponseMessage {
+	t.Helper()
+	responseMessage, err := Call(t, svc, operName, request, response)
+	if err != nil {
+		t.Fatalf("%s.%s failed: %v", svc.Name(), operName, err)
+	}
+	return responseMessage
+}
+
+//MustFail runs the request and fails the test unless the operation failed with the error type
+func MustFail(t testing.TB, svc msvc.IMicroService, operName string, request interface{}, errorType string) *msvc.Error {
+	t.Helper()
+	_, err := Call(t, svc, operName, request, nil)
+	return AssertErrorType(t, err, errorType)
+}
+
+//AssertErrorType fails the test unless err is a *msvc.Error with the error type
+func AssertErrorType(t testing.TB, err error, errorType string) *msvc.Error {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("Expected error type %s but succeeded", errorType)
+	}
+	e, ok := err.(*msvc.Error)
+	if !ok {
+		t.Fatalf("Expected error type %s but got %T: %v", errorType, err, err)
+	}
+	if e.Type != errorType {
+		t.Fatalf("Expected error type %s but got %v", errorType, e)
+	}
+	return e
+}
+
+//AssertFieldError fails the test unless the error lists the field as invalid
+//according to the rule (see msvc.FieldError), or any rule when rule is ""
+func AssertFieldError(t testing.TB, e *msvc.Error, field string, rule string) {
+	t.Helper()
+	if e == nil {
+		t.Fatalf("Expected field error %s %s but got no error", field, rule)
+	}
+	for _, fieldError := range e.Fields {
+		if fieldError.Field == field && (rule == "" || fieldError.Rule == rule) {
+			return
+		}
+	}
+	t.Fatalf("Expected field error %s %s but got %+v", field, rule, e.Fields)
+}
@@ -0,0 +1,192 @@
+package msvc
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//OpenAPIVersion is the version of the generated OpenAPI documents,
+//which uses JSON Schema draft 2020-12 (see Schema) to describe data
+const OpenAPIVersion = "3.1.0"
+
+//OpenAPIPath is the path where the rest server serves the OpenAPI document
+const OpenAPIPath = "/openapi.json"
+
+//OpenAPIDocument describes the HTTP REST interface of a micro-service
+type OpenAPIDocument struct {
+	OpenAPI string                     `json:"openapi"`
+	Info    OpenAPIInfo                `json:"info"`
+	Servers []OpenAPIServer            `json:"servers,omitempty"`
+	Paths   map[string]OpenAPIPathItem `json:"paths"`
+}
+
+//OpenAPIInfo ...
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+//OpenAPIServer ...
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+//OpenAPIPathItem ...
+type OpenAPIPathItem struct {
+	Post *OpenAPIOperation `json:"post,omitempty"`
+}
+
+//OpenAPIOperation ...
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	RequestBody OpenAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+//OpenAPIRequestBody ...
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+//OpenAPIResponse ...
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+//OpenAPIMediaType ...
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+//OpenAPI returns the OpenAPI document of the rest server interface
+//with a POST /<service>/<oper> path for every operation
+//and for the job operations when there are asynchronous operations,
+//plus the path for batch requests
+func (msvc msvc) OpenAPI(serverURLs ...string) OpenAPIDocument {
+	doc := OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:   msvc.name,
+			Version: "1",
+		},
+		Paths: map[string]OpenAPIPathItem{},
+	}
+	for _, url := range serverURLs {
+		doc.Servers = append(doc.Servers, OpenAPIServer{URL: url})
+	}
+	operNames := msvc.operNames()
+	if len(msvc.asyncOpers) > 0 {
+		operNames = append(operNames, statusOperName, resultOperName, cancelOperName)
+	}
+	for _, operName := range operNames {
+		doc.Paths["/"+msvc.name+"/"+operName] = OpenAPIPathItem{
+			Post: msvc.openAPIOperation(operName),
+		}
+	}
+	doc.Paths["/"+msvc.name+"/"+BatchOperName] = OpenAPIPathItem{
+		Post: msvc.openAPIBatchOperation(),
+	}
+	return doc
+} //msvc.OpenAPI()
+
+//WriteOpenAPI writes the OpenAPI document into the named file
+//so that it can be imported elsewhere without running the service
+func (msvc msvc) WriteOpenAPI(fileName string, serverURLs ...string) error {
+	return writeJSONFile(fileName, msvc.OpenAPI(serverURLs...))
+}
+
+func (msvc msvc) openAPIOperation(operName string) *OpenAPIOperation {
+	operTmpl := msvc.operTmpl[operName]
+	requestSchema := schemaOf(reflect.TypeOf(operTmpl), nil)
+	responseSchema := schemaOf(msvc.responseType(operTmpl, operName), nil)
+
+	//request envelope with the oper request data
+	requestMessageSchema := schemaOf(reflect.TypeOf(RequestMessage{}), nil)
+	requestMessageSchema.Properties["request"] = requestSchema
+
+	o := &OpenAPIOperation{
+		OperationID: operName,
+		Summary:     msvc.name + "." + operName,
+		RequestBody: OpenAPIRequestBody{
+			Required: true,
+			Content:  jsonContent(requestMessageSchema),
+		},
+		Responses: map[string]OpenAPIResponse{
+			strconv.Itoa(http.StatusOK): {
+				Description: "Success",
+				Content:     jsonContent(responseMessageSchema(requestSchema, responseSchema, nil)),
+			},
+		},
+	}
+
+	//error results grouped by HTTP status
+	resultTypes := map[int][]string{}
+	for _, r := range append(append([]IResult{}, msvc.operResults[operName]...), frameworkResults...) {
+		status := r.HTTPStatus()
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		resultTypes[status] = append(resultTypes[status], r.Type())
+	}
+	for status, types := range resultTypes {
+		sort.Strings(types)
+		o.Responses[strconv.Itoa(status)] = OpenAPIResponse{
+			Description: fmt.Sprintf("Error type %s", strings.Join(types, ", ")),
+			Content:     jsonContent(responseMessageSchema(requestSchema, nil, types)),
+		}
+	}
+	return o
+} //msvc.openAPIOperation()
+
+//openAPIBatchOperation describes the batch requests (see HandleBatchJSON())
+func (msvc msvc) openAPIBatchOperation() *OpenAPIOperation {
+	return &OpenAPIOperation{
+		OperationID: BatchOperName,
+		Summary:     msvc.name + "." + BatchOperName,
+		RequestBody: OpenAPIRequestBody{
+			Required: true,
+			Content:  jsonContent(schemaOf(reflect.TypeOf(BatchRequest{}), nil)),
+		},
+		Responses: map[string]OpenAPIResponse{
+			strconv.Itoa(http.StatusOK): {
+				Description: "Responses of all items, including items that failed",
+				Content:     jsonContent(schemaOf(reflect.TypeOf(BatchResponse{}), nil)),
+			},
+			strconv.Itoa(http.StatusBadRequest): {
+				Description: "Error type decodeJSONBatch",
+				Content:     jsonContent(schemaOf(reflect.TypeOf(BatchResponse{}), nil)),
+			},
+		},
+	}
+} //msvc.openAPIBatchOperation()
+
+//responseMessageSchema returns the response envelope schema with the oper request (echo),
+//response schema (if successful) or the enumerated error types (if failed)
+func responseMessageSchema(requestSchema, responseSchema *Schema, errorTypes []string) *Schema {
+	s := schemaOf(reflect.TypeOf(ResponseMessage{}), nil)
+	s.Properties["request"] = requestSchema
+	if responseSchema != nil {
+		delete(s.Properties, "error")
+		s.Properties["response"] = responseSchema
+	} else {
+		delete(s.Properties, "response")
+		errorTypeSchema := s.Properties["error"].Properties["type"]
+		for _, t := range errorTypes {
+			errorTypeSchema.Enum = append(errorTypeSchema.Enum, t)
+		}
+	}
+	return s
+}
+
+func jsonContent(s *Schema) map[string]OpenAPIMediaType {
+	return map[string]OpenAPIMediaType{
+		"application/json": {Schema: s},
+	}
+}
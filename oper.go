@@ -1,7 +1,9 @@
 package msvc
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
@@ -11,17 +13,62 @@ type IOper interface {
 	Results() []IResult
 
 	//Validate the operation request before it is called
+	//(after the rules in the validate tags of the fields were applied, see FieldError)
 	Validate() error
 
 	//Run the operation to return the (optional) response data or an error
+	//(see also IOperWithContext)
 	Run() (interface{}, *Error)
 
 	//ErrorMessage can be used on a request to respond with an error
 	ErrorMessage(errorType string, err error) ResponseMessage
 }
 
-//Oper ...
-type Oper struct{}
+//Oper should be embedded in all operation structs.
+//The framework sets the request context in it before the operation is validated and run
+type Oper struct {
+	//runtime is a pointer so that logging the oper with %+v does not print the context
+	runtime *operRuntime
+}
+
+//operRuntime is the run-time data of the embedded Oper
+type operRuntime struct {
+	ctx context.Context
+}
+
+//Context returns the request context (see IOperWithContext) so that operations
+//that only implement Run() can also observe the deadline and request meta data
+func (oper Oper) Context() context.Context {
+	if oper.runtime == nil {
+		return context.Background()
+	}
+	return oper.runtime.ctx
+}
+
+//Log returns the request logger (see LoggerFromContext) to use in Validate() and Run()
+func (oper Oper) Log() ILogger {
+	return LoggerFromContext(oper.Context())
+}
+
+//Config returns the configuration of the operation (see IOperWithConfig)
+func (oper Oper) Config() interface{} {
+	return ConfigFromContext(oper.Context())
+}
+
+//Validate is the default implementation for operations that only
+//need the rules in the validate tags of their fields (see FieldError)
+func (oper Oper) Validate() error {
+	return nil
+}
+
+//Run is the default implementation for operations that implement IOperWithContext
+//and therefore do not need to implement Run() as well
+func (oper Oper) Run() (interface{}, *Error) {
+	return nil, &Error{
+		Type:        "notImplemented",
+		Description: "Operation does not implement Run()",
+	}
+}
 
 //ErrorMessage ...
 func (oper Oper) ErrorMessage(errorType string, err error) ResponseMessage {
@@ -33,3 +80,23 @@ func (oper Oper) ErrorMessage(errorType string, err error) ResponseMessage {
 		},
 	}
 }
+
+//embeddedOperType is the reflect type of the embedded Oper
+var embeddedOperType = reflect.TypeOf(Oper{})
+
+//setOperContext stores the context in the embedded Oper of the new operation struct,
+//which may also be embedded in another struct that is embedded in the operation.
+//operStructPtrValue must be a pointer to the operation struct
+func setOperContext(operStructPtrValue reflect.Value, ctx context.Context) {
+	operStructValue := operStructPtrValue.Elem()
+	if operStructValue.Kind() != reflect.Struct {
+		return
+	}
+	f, ok := operStructValue.Type().FieldByName(embeddedOperType.Name())
+	if !ok || !f.Anonymous || f.Type != embeddedOperType {
+		return
+	}
+	if v, ok := fieldByIndex(operStructValue, f.Index); ok {
+		v.Addr().Interface().(*Oper).runtime = &operRuntime{ctx: ctx}
+	}
+} //setOperContext()
@@ -0,0 +1,83 @@
+package msvc
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jansemmelink/config"
+	"github.com/jansemmelink/log"
+)
+
+//IOperWithConfig may be implemented by an operation that needs configuration.
+//The configuration is loaded from the config set section "<service>-<oper>",
+//e.g. ./conf/template-hello.json, and must be valid for Serve() to start.
+//During Validate() and Run(), the oper gets it from Oper.Config() or ConfigFromContext()
+type IOperWithConfig interface {
+	//ConfigTmpl returns the config struct to load, e.g. helloConfig{} or &helloConfig{}
+	//when Validate() must be called on a pointer to set defaults
+	ConfigTmpl() config.IValidator
+}
+
+//operConfigs holds the current configuration of all operations
+//as map[string]interface{} that is replaced as a whole when reloaded
+type operConfigs struct {
+	current atomic.Value
+}
+
+func newOperConfigs() *operConfigs {
+	oc := &operConfigs{}
+	oc.current.Store(map[string]interface{}{})
+	return oc
+}
+
+//get returns the current configuration of the named operation or nil if not loaded
+func (oc *operConfigs) get(operName string) interface{} {
+	return oc.current.Load().(map[string]interface{})[operName]
+}
+
+//set replaces the configuration of all operations
+func (oc *operConfigs) set(configs map[string]interface{}) {
+	oc.current.Store(configs)
+}
+
+//operConfigSection is the name of the config set section of an operation
+func (msvc msvc) operConfigSection(operName string) string {
+	return msvc.name + "-" + operName
+}
+
+//loadOperConfigs loads and validates the configuration of all operations
+//that implement IOperWithConfig from the config set
+func (msvc msvc) loadOperConfigs(cs config.ISet) (map[string]interface{}, error) {
+	configs := map[string]interface{}{}
+	for operName, operTmpl := range msvc.operTmpl {
+		operWithConfig, ok := operTmpl.(IOperWithConfig)
+		if !ok {
+			continue
+		}
+		section := msvc.operConfigSection(operName)
+		operConfiguration, err := cs.Add(section, operWithConfig.ConfigTmpl())
+		if err != nil {
+			return nil, log.Wrapf(err, "MicroService[%s].oper[%s] config \"%s\" not valid", msvc.name, operName, section)
+		}
+		configs[operName] = operConfiguration.Current()
+		log.Debugf("Loaded %s: %+v", section, configs[operName])
+	}
+	return configs, nil
+} //msvc.loadOperConfigs()
+
+//LoadConfig loads the configuration of all operations from the config set.
+//It is called by Serve() and may be called before HandleJSON() is used without Serve()
+func (msvc msvc) LoadConfig() error {
+	configs, err := msvc.loadOperConfigs(msvc.configSet)
+	if err != nil {
+		return err
+	}
+	msvc.operConfigs.set(configs)
+	return nil
+}
+
+//ConfigFromContext returns the configuration of the operation being served
+//or nil if the operation does not implement IOperWithConfig
+func ConfigFromContext(ctx context.Context) interface{} {
+	return ctx.Value(contextKeyConfig)
+}
@@ -0,0 +1,53 @@
+package msvc
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+//operCounter counts events, like panics recovered, in each operation
+type operCounter struct {
+	mutex sync.Mutex
+	count map[string]int
+}
+
+func newOperCounter() *operCounter {
+	return &operCounter{
+		count: make(map[string]int),
+	}
+}
+
+func (pc *operCounter) inc(operName string) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	pc.count[operName]++
+}
+
+func (pc *operCounter) get(operName string) int {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	return pc.count[operName]
+}
+
+//recovered is called with the value recovered from a panic in the named operation
+//to log the stack trace and return an internal error. The panic value and stack trace
+//is only returned to the consumer when debug is enabled
+func (msvc msvc) recovered(operName string, uuid string, r interface{}) ResponseMessage {
+	const size = 64 << 10 //64k
+	buf := make([]byte, size)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	msvc.panics.inc(operName)
+	NewLogger(uuid).Errorf("MicroService[%s].oper[%s] panic: %v\n%s", msvc.name, operName, r, buf)
+
+	e := &Error{
+		Type:        "internalError",
+		Description: "Internal software error",
+	}
+	if msvc.settings.debug {
+		e.Description = fmt.Sprintf("panic: %v", r)
+		e.Stack = string(buf)
+	}
+	return ResponseMessage{Error: e}
+} //msvc.recovered()
@@ -0,0 +1,60 @@
+package msvc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+)
+
+//panicOper panics with the value in the request
+type panicOper struct {
+	msvc.Oper
+	Value string `json:"value"`
+}
+
+func (o panicOper) Results() []msvc.IResult { return nil }
+
+func (o panicOper) Run() (interface{}, *msvc.Error) {
+	panic(o.Value)
+}
+
+func TestPanicRecovered(t *testing.T) {
+	tests := []struct {
+		name            string
+		debug           bool
+		wantDescription string
+		wantStack       bool
+	}{
+		{name: "hidden", debug: false, wantDescription: "Internal software error", wantStack: false},
+		{name: "debug", debug: true, wantDescription: "panic: boom", wantStack: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := msvc.New("test").
+				WithOper("panic", panicOper{}).
+				WithDebug(test.debug)
+			response := svc.HandleJSON("panic", []byte(`{"request":{"value":"boom"}}`))
+			if response.Error == nil || response.Error.Type != "internalError" {
+				t.Fatalf("Expected internalError but got %+v", response.Error)
+			}
+			if response.Error.Description != test.wantDescription {
+				t.Fatalf("Expected description \"%s\" but got \"%s\"", test.wantDescription, response.Error.Description)
+			}
+			if (len(response.Error.Stack) > 0) != test.wantStack {
+				t.Fatalf("Expected stack %v but got \"%s\"", test.wantStack, response.Error.Stack)
+			}
+			if svc.Panics("panic") != 1 {
+				t.Fatalf("Expected 1 panic, got %d", svc.Panics("panic"))
+			}
+			metrics := bytes.Buffer{}
+			if err := svc.WriteMetrics(&metrics); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(metrics.String(), `msvc_panics_total{service="test",oper="panic"} 1`) {
+				t.Fatalf("Expected the panic in the metrics:\n%s", metrics.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package msvc
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jansemmelink/log"
+)
+
+//RateLimit is a token bucket that allows Burst requests at once
+//and refills at Rate requests per second
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+//AnonymousConsumer is the consumer name used for rate limits
+//of requests without Header.Consumer.Name
+const AnonymousConsumer = "_anonymous"
+
+//maxRateLimitBuckets limits the memory used for buckets. When reached, consumers
+//without a bucket share the bucket of AnonymousConsumer until buckets are evicted
+const maxRateLimitBuckets = 10000
+
+//rateLimitSweepInterval is how often buckets that refilled are evicted
+const rateLimitSweepInterval = 10 * time.Second
+
+//WithRateLimit limits the rate of requests from a consumer to an operation.
+//Use consumerName "" for any consumer and AnonymousConsumer for requests
+//without a consumer name, and operName "" for the total over all operations.
+//Every consumer gets its own bucket. When several limits match a request,
+//the most specific one applies in this order: consumer and oper, consumer,
+//oper, then the default limit for any consumer and any oper.
+//
+//Consumer names are not authenticated: a consumer can avoid its limit by
+//sending another name, so only the default limit protects the service.
+//Buckets are evicted when they refilled, and when there are too many buckets,
+//new consumer names share the bucket of AnonymousConsumer.
+func (msvc msvc) WithRateLimit(consumerName string, operName string, limit RateLimit) IMicroService {
+	if operName != "" {
+		if _, ok := msvc.operTmpl[operName]; !ok {
+			panic(log.Wrapf(nil, "MicroService[%s].oper[%s] does not exist", msvc.name, operName))
+		}
+	}
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		panic(log.Wrapf(nil, "MicroService[%s] rate limit for consumer \"%s\" oper \"%s\" needs rate>0 and burst>0", msvc.name, consumerName, operName))
+	}
+	msvc.rateLimiter.limits[rateLimitKey{consumerName: consumerName, operName: operName}] = limit
+	return msvc
+}
+
+//rateLimitKey identifies a configured limit or a bucket
+type rateLimitKey struct {
+	consumerName string
+	operName     string
+}
+
+type rateLimiter struct {
+	limits    map[rateLimitKey]RateLimit
+	mutex     sync.Mutex
+	buckets   map[rateLimitKey]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		limits:  make(map[rateLimitKey]RateLimit),
+		buckets: make(map[rateLimitKey]*tokenBucket),
+	}
+}
+
+//allow takes a token from the consumer's bucket for the operation
+//or returns how long to wait before a token will be available
+func (rl *rateLimiter) allow(consumerName string, operName string, now time.Time) (bool, time.Duration) {
+	if consumerName == "" {
+		consumerName = AnonymousConsumer
+	}
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	if now.Sub(rl.lastSweep) >= rateLimitSweepInterval {
+		rl.sweep(now)
+	}
+
+	bucket, ok := rl.bucket(consumerName, operName, now)
+	if !ok {
+		return true, 0
+	}
+	return bucket.take(now)
+}
+
+//bucket returns the bucket of the consumer for the operation or false if not limited.
+//The bucket is per consumer, shared by all opers when the limit is for all opers
+func (rl *rateLimiter) bucket(consumerName string, operName string, now time.Time) (*tokenBucket, bool) {
+	limitKey, ok := rl.find(consumerName, operName)
+	if !ok {
+		return nil, false
+	}
+	bucketKey := rateLimitKey{consumerName: consumerName, operName: limitKey.operName}
+	if bucket, ok := rl.buckets[bucketKey]; ok {
+		return bucket, true
+	}
+	if len(rl.buckets) >= maxRateLimitBuckets && consumerName != AnonymousConsumer {
+		return rl.bucket(AnonymousConsumer, operName, now)
+	}
+	limit := rl.limits[limitKey]
+	bucket := &tokenBucket{limit: limit, tokens: float64(limit.Burst), updated: now}
+	rl.buckets[bucketKey] = bucket
+	return bucket, true
+}
+
+//sweep evicts the buckets that refilled, as they are the same as new buckets
+func (rl *rateLimiter) sweep(now time.Time) {
+	rl.lastSweep = now
+	for bucketKey, bucket := range rl.buckets {
+		if bucket.refill(now) >= float64(bucket.limit.Burst) {
+			delete(rl.buckets, bucketKey)
+		}
+	}
+}
+
+//find the most specific limit for the request
+func (rl *rateLimiter) find(consumerName string, operName string) (rateLimitKey, bool) {
+	for _, key := range []rateLimitKey{
+		{consumerName: consumerName, operName: operName},
+		{consumerName: consumerName},
+		{operName: operName},
+		{},
+	} {
+		if _, ok := rl.limits[key]; ok {
+			return key, true
+		}
+	}
+	return rateLimitKey{}, false
+}
+
+type tokenBucket struct {
+	limit   RateLimit
+	tokens  float64
+	updated time.Time
+}
+
+//refill adds the tokens for the time since the last update and returns the tokens
+func (b *tokenBucket) refill(now time.Time) float64 {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate)
+	b.updated = now
+	return b.tokens
+}
+
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	if b.refill(now) >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
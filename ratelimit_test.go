@@ -0,0 +1,35 @@
+package msvc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimitBucketsAreBounded(t *testing.T) {
+	rl := newRateLimiter()
+	rl.limits[rateLimitKey{}] = RateLimit{Rate: 1, Burst: 1}
+	now := time.Now()
+	for i := 0; i < maxRateLimitBuckets; i++ {
+		if ok, _ := rl.allow(fmt.Sprintf("consumer-%d", i), "oper", now); !ok {
+			t.Fatalf("Expected consumer-%d to be allowed", i)
+		}
+	}
+
+	//new names share the anonymous bucket when there are too many buckets
+	if ok, _ := rl.allow("another", "oper", now); !ok {
+		t.Fatalf("Expected the first request in the anonymous bucket to be allowed")
+	}
+	if ok, _ := rl.allow("yet-another", "oper", now); ok {
+		t.Fatalf("Expected a new name to be limited by the anonymous bucket")
+	}
+	if len(rl.buckets) > maxRateLimitBuckets+1 {
+		t.Fatalf("Expected at most %d buckets, got %d", maxRateLimitBuckets+1, len(rl.buckets))
+	}
+
+	//refilled buckets are evicted
+	rl.allow("consumer-0", "oper", now.Add(rateLimitSweepInterval))
+	if len(rl.buckets) != 1 {
+		t.Fatalf("Expected refilled buckets to be evicted, got %d buckets", len(rl.buckets))
+	}
+}
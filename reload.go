@@ -0,0 +1,76 @@
+package msvc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jansemmelink/config"
+	"github.com/jansemmelink/log"
+)
+
+//configDir is where the micro-service configuration files are loaded from
+const configDir = "./conf"
+
+func newConfigSet() config.ISet {
+	return config.NewSet().MustSource("files", configDir)
+}
+
+//WithConfigWatch makes Serve() check the configuration files for changes at the
+//specified interval and reload when they changed. Without it, the configuration
+//is only reloaded when the process receives SIGHUP.
+func (msvc msvc) WithConfigWatch(interval time.Duration) IMicroService {
+	msvc.settings.configWatch = interval
+	return msvc
+}
+
+//configModified returns a signature of the configuration files
+//that changes when any file is added, removed or modified
+func configModified() string {
+	files, err := ioutil.ReadDir(configDir)
+	if err != nil {
+		return ""
+	}
+	signature := ""
+	for _, file := range files {
+		signature += fmt.Sprintf("%s:%d:%d;", file.Name(), file.Size(), file.ModTime().UnixNano())
+	}
+	return signature
+}
+
+//configExists is true when there is a file for the named configuration in configDir,
+//e.g. "rest.json" for "rest", so that a removed configuration can be told apart
+//from one that is not valid
+func configExists(name string) bool {
+	files, err := ioutil.ReadDir(configDir)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())) == name {
+			return true
+		}
+	}
+	return false
+}
+
+//reload the configuration of the operations and the servers from a new config set.
+//When any operation configuration is not valid, it is rejected and the old
+//configuration of all operations remains in use. Servers are only restarted when
+//their own configuration changed (see servers.update())
+func (msvc msvc) reload(servers *servers) error {
+	log.Debugf("MicroService[%s] reloading configuration...", msvc.name)
+	cs := newConfigSet()
+	configs, err := msvc.loadOperConfigs(cs)
+	if err != nil {
+		return log.Wrapf(err, "MicroService[%s] new config rejected", msvc.name)
+	}
+	configured, errs := configuredServers(cs)
+
+	msvc.operConfigs.set(configs)
+	servers.update(configured, errs)
+	log.Debugf("MicroService[%s] reloaded configuration", msvc.name)
+	return nil
+} //msvc.reload()
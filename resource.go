@@ -0,0 +1,137 @@
+package msvc
+
+import (
+	"reflect"
+
+	"github.com/jansemmelink/log"
+)
+
+//IResources provides the shared resources registered with IMicroService.WithResource()
+type IResources interface {
+	//Resource returns the named resource or nil if not registered
+	Resource(name string) interface{}
+}
+
+//IOperWithInit may be implemented by an operation to get resources when it is created,
+//e.g. to store resources in unexported fields or to create something from them.
+//Init is called on every new operation instance after the inject tags were applied
+//and before the request is validated
+type IOperWithInit interface {
+	Init(resources IResources) error
+}
+
+//WithResource registers a shared resource, e.g. a DB pool or HTTP client, that is
+//injected into every new operation instance in exported fields with tag inject:"<name>", e.g.:
+//
+//	DB *sql.DB `json:"-" inject:"db"`
+//
+//and available to operations that implement IOperWithInit.
+//Fields with an inject tag must be tagged json:"-", else WithOper() panics
+func (msvc msvc) WithResource(name string, resource interface{}) IMicroService {
+	if len(name) == 0 || resource == nil {
+		panic("cannot add resource without a name and a value")
+	}
+	if _, ok := msvc.resources[name]; ok {
+		panic(log.Wrapf(nil, "MicroService[%s].resource[%s] already exists", msvc.name, name))
+	}
+	msvc.resources[name] = resource
+	return msvc
+}
+
+//Resource returns the named resource or nil if not registered
+func (msvc msvc) Resource(name string) interface{} {
+	return msvc.resources[name]
+}
+
+//inject the resources into a new operation instance
+//operStructPtrValue must be a pointer to the operation struct
+func (msvc msvc) inject(operStructPtrValue reflect.Value) error {
+	if operStructPtrValue.Elem().Kind() == reflect.Struct {
+		if err := msvc.injectFields(operStructPtrValue.Elem()); err != nil {
+			return err
+		}
+	}
+	if operWithInit, ok := operStructPtrValue.Interface().(IOperWithInit); ok {
+		if err := operWithInit.Init(msvc); err != nil {
+			return log.Wrapf(err, "Init() failed")
+		}
+	}
+	return nil
+} //msvc.inject()
+
+//injectFields sets the fields with inject tags in the struct and its embedded structs
+func (msvc msvc) injectFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		resourceName, ok := f.Tag.Lookup("inject")
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				if err := msvc.injectFields(v.Field(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		resource, ok := msvc.resources[resourceName]
+		if !ok {
+			return log.Wrapf(nil, "%s.%s needs resource \"%s\" which is not registered", t.Name(), f.Name, resourceName)
+		}
+		resourceValue := reflect.ValueOf(resource)
+		if !resourceValue.Type().AssignableTo(f.Type) {
+			return log.Wrapf(nil, "%s.%s of type %v cannot be set to resource \"%s\" of type %T", t.Name(), f.Name, f.Type, resourceName, resource)
+		}
+		if !v.Field(i).CanSet() {
+			return log.Wrapf(nil, "%s.%s must be exported to inject resource \"%s\"", t.Name(), f.Name, resourceName)
+		}
+		v.Field(i).Set(resourceValue)
+	}
+	return nil
+} //msvc.injectFields()
+
+//checkInjectTags returns an error if a field with an inject tag is not tagged json:"-",
+//as resources must not be decoded from the request or echoed to the consumer
+func checkInjectTags(t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		resourceName, ok := f.Tag.Lookup("inject")
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				if err := checkInjectTags(f.Type); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if f.Tag.Get("json") != "-" {
+			return log.Wrapf(nil, "%s.%s injects resource \"%s\" and must be tagged json:\"-\"", t.Name(), f.Name, resourceName)
+		}
+	}
+	return nil
+} //checkInjectTags()
+
+//checkInjection returns an error if resources cannot be injected into any operation
+//so that Serve() fails before requests are received
+func (msvc msvc) checkInjection() error {
+	for operName, operTmpl := range msvc.operTmpl {
+		if err := msvc.inject(newOperValue(operTmpl)); err != nil {
+			return log.Wrapf(err, "MicroService[%s].oper[%s] cannot be created", msvc.name, operName)
+		}
+	}
+	return nil
+}
+
+//newOperValue returns a pointer to a new zero operation struct of the template type
+func newOperValue(operTmpl IOper) reflect.Value {
+	operType := reflect.TypeOf(operTmpl)
+	if operType.Kind() == reflect.Ptr {
+		return reflect.New(operType.Elem())
+	}
+	return reflect.New(operType)
+}
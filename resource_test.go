@@ -0,0 +1,65 @@
+package msvc_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jansemmelink/msvc"
+)
+
+//secretOper gets an injected resource that must never be sent back to the consumer
+type secretOper struct {
+	msvc.Oper
+	Name   string `json:"name"`
+	Secret string `json:"-" inject:"secret"`
+}
+
+func (o secretOper) Results() []msvc.IResult { return nil }
+
+func (o secretOper) Run() (interface{}, *msvc.Error) {
+	if o.Secret != "TOPSECRET" {
+		return nil, &msvc.Error{Type: "notInjected"}
+	}
+	return "ok", nil
+}
+
+func TestInjectedResourceNotEchoed(t *testing.T) {
+	svc := msvc.New("test").
+		WithResource("secret", "TOPSECRET").
+		WithOper("secret", secretOper{})
+	response := svc.HandleJSON("secret", []byte(`{"header":{"timestamp":"`+time.Now().Format(msvc.TimestampFormat)+`","echo-request":true},"request":{"name":"x"}}`))
+	if response.Error != nil {
+		t.Fatalf("Expected success but got %+v", response.Error)
+	}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(jsonResponse), `"request":{"name":"x"}`) {
+		t.Fatalf("Expected the request to be echoed, got %s", jsonResponse)
+	}
+	if strings.Contains(string(jsonResponse), "TOPSECRET") {
+		t.Fatalf("Injected resource echoed in %s", jsonResponse)
+	}
+}
+
+//leakyOper has an injected field that would be decoded from and encoded in JSON
+type leakyOper struct {
+	msvc.Oper
+	Secret string `inject:"secret"`
+}
+
+func (o leakyOper) Results() []msvc.IResult { return nil }
+
+func (o leakyOper) Run() (interface{}, *msvc.Error) { return nil, nil }
+
+func TestInjectedFieldMustNotBeJSON(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected WithOper() to panic")
+		}
+	}()
+	msvc.New("test").WithResource("secret", "TOPSECRET").WithOper("leaky", leakyOper{})
+}
@@ -0,0 +1,37 @@
+package msvc
+
+import (
+	"time"
+)
+
+//responseMessage completes the envelope of the response to the request message:
+//
+//	the response header echoes the request UUID (or the one assigned) and consumer,
+//	identifies this service as the provider,
+//	and contains the duration since the request timestamp,
+//	and when echo-request was specified, the request data is copied into the response
+func (msvc msvc) responseMessage(requestMessage RequestMessage, uuid string, requestTimestamp time.Time, responseMessage ResponseMessage) ResponseMessage {
+	now := time.Now()
+	header := &ResponseHeader{
+		Header: Header{
+			Timestamp: now.Format(TimestampFormat),
+			Provider: &Provider{
+				Name:     msvc.name,
+				Instance: msvc.instance,
+			},
+		},
+		Dur: now.Sub(requestTimestamp),
+	}
+	header.UUID = uuid
+	if requestMessage.Header != nil {
+		header.Consumer = requestMessage.Header.Consumer
+		if requestMessage.Header.EchoRequest {
+			responseMessage.Request = requestMessage.Request
+		}
+	}
+	if len(header.UUID) == 0 {
+		header.UUID = NewUUID()
+	}
+	responseMessage.Header = header
+	return responseMessage
+} //msvc.responseMessage()
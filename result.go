@@ -1,5 +1,84 @@
 package msvc
 
-//IResult is returned from an operation to indicate the outcome
+import (
+	"net/http"
+
+	"github.com/jansemmelink/log"
+)
+
+//IResult describes one error result that an operation may return in Error.Type.
+//Success is implied and not listed as a result.
 type IResult interface {
+	//Type is the name of the result used in Error.Type
+	Type() string
+
+	//Description explains when this result is returned
+	Description() string
+
+	//HTTPStatus is the status code used when the result is returned over HTTP
+	HTTPStatus() int
+}
+
+//Result creates an IResult to be listed by IOper.Results()
+func Result(resultType string, httpStatus int, description string) IResult {
+	return result{
+		resultType:  resultType,
+		httpStatus:  httpStatus,
+		description: description,
+	}
+}
+
+type result struct {
+	resultType  string
+	httpStatus  int
+	description string
+}
+
+func (r result) Type() string        { return r.resultType }
+func (r result) Description() string { return r.description }
+func (r result) HTTPStatus() int     { return r.httpStatus }
+
+//frameworkResults are the results that the framework may return for any operation,
+//so operations need not list them
+var frameworkResults = []IResult{
+	Result("decodeJSONRequestHeader", http.StatusBadRequest, "The request header could not be decoded."),
+	Result("invalidRequestHeader", http.StatusBadRequest, "The request header is not valid or the request expired."),
+	Result("unknownOper", http.StatusNotFound, "The operation does not exist in this micro-service."),
+	Result("decodeJSONRequestData", http.StatusBadRequest, "The request data could not be decoded into the operation request."),
+	Result("operMissingValidator", http.StatusInternalServerError, "The operation does not implement IOper."),
+	Result("invalidRequest", http.StatusBadRequest, "The request data is not valid for the operation."),
+	Result("internalError", http.StatusInternalServerError, "The operation failed unexpectedly."),
+	Result("notImplemented", http.StatusNotImplemented, "The operation is not implemented."),
+	Result("terminating", http.StatusServiceUnavailable, "The micro-service is terminating and does not accept new requests."),
+	Result("timeout", http.StatusGatewayTimeout, "The operation did not complete within the max-duration of the request or the operation timeout."),
+	Result("overloaded", http.StatusServiceUnavailable, "Too many requests are in progress or queued for the operation."),
+	Result("rateLimited", http.StatusTooManyRequests, "The consumer exceeded its rate limit and may retry after Error.RetryAfter."),
+	Result("undeclaredResult", http.StatusInternalServerError, "The operation returned an error type that it did not declare in its results."),
+}
+
+//FrameworkResults returns the results that the framework may return for any operation
+func FrameworkResults() []IResult {
+	return append([]IResult{}, frameworkResults...)
+}
+
+//findResult returns the result with the specified type from the list or nil if not found
+func findResult(results []IResult, resultType string) IResult {
+	for _, r := range results {
+		if r.Type() == resultType {
+			return r
+		}
+	}
+	return nil
+}
+
+//checkResults panics if the results listed by an operation are not usable
+func checkResults(serviceName, operName string, results []IResult) {
+	for i, r := range results {
+		if r == nil || len(r.Type()) == 0 {
+			panic(log.Wrapf(nil, "MicroService[%s].oper[%s].Results()[%d] has no type", serviceName, operName, i))
+		}
+		if findResult(results[:i], r.Type()) != nil {
+			panic(log.Wrapf(nil, "MicroService[%s].oper[%s].Results() has duplicate type \"%s\"", serviceName, operName, r.Type()))
+		}
+	}
 }
@@ -0,0 +1,213 @@
+package msvc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"github.com/jansemmelink/log"
+)
+
+//SchemaDialect is the JSON Schema version of the generated schemas
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+//Schema is a JSON Schema document generated from the json and doc tags of a Go type
+type Schema struct {
+	Dialect     string             `json:"$schema,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Additional  *Schema            `json:"additionalProperties,omitempty"`
+
+	//validation keywords from the validate tags (see FieldError)
+	MinLength     *int     `json:"minLength,omitempty"`
+	MaxLength     *int     `json:"maxLength,omitempty"`
+	MinItems      *int     `json:"minItems,omitempty"`
+	MaxItems      *int     `json:"maxItems,omitempty"`
+	MinProperties *int     `json:"minProperties,omitempty"`
+	MaxProperties *int     `json:"maxProperties,omitempty"`
+	Minimum       *float64 `json:"minimum,omitempty"`
+	Maximum       *float64 `json:"maximum,omitempty"`
+	Pattern       string   `json:"pattern,omitempty"`
+}
+
+//IOperWithResponse may be implemented by an operation to declare the type of response
+//data returned from Run(), so that its schema can be generated. If not implemented,
+//the response is described as any JSON value.
+type IOperWithResponse interface {
+	//Response returns a value of the type of response data, e.g. MyResponse{}
+	Response() interface{}
+}
+
+//NewSchema generates the JSON Schema of a Go type
+func NewSchema(title string, t reflect.Type) *Schema {
+	s := schemaOf(t, nil)
+	s.Dialect = SchemaDialect
+	s.Title = title
+	return s
+}
+
+//schemaOf returns the schema of a Go type
+//visited contains the struct types being described to stop recursion in recursive types
+func schemaOf(t reflect.Type, visited []reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{} //any value
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s := &Schema{Type: jsonType(t)}
+	switch s.Type {
+	case "string":
+		if t == timeType {
+			s.Format = "date-time"
+		}
+	case "array":
+		s.Items = schemaOf(t.Elem(), visited)
+	case "object":
+		if t.Kind() == reflect.Map {
+			s.Additional = schemaOf(t.Elem(), visited)
+			break
+		}
+		for _, v := range visited {
+			if v == t {
+				return &Schema{Type: "object"} //recursive type
+			}
+		}
+		visited = append(visited, t)
+		s.Properties = map[string]*Schema{}
+		for _, f := range jsonFields(t) {
+			fs := schemaOf(f.field.Type, visited)
+			fs.Description = f.field.Tag.Get("doc")
+			if fr, err := rules(f.field.Tag.Get("validate")); err == nil {
+				fr.addToSchema(fs)
+				if fr.required {
+					s.Required = append(s.Required, f.name)
+				}
+			}
+			s.Properties[f.name] = fs
+		}
+	}
+	return s
+} //schemaOf()
+
+//addToSchema adds the validation keywords for the rules to the schema of the field
+func (fr fieldRules) addToSchema(s *Schema) {
+	intPtr := func(f *float64) *int {
+		if f == nil {
+			return nil
+		}
+		i := int(*f)
+		return &i
+	}
+	switch s.Type {
+	case "string":
+		s.MinLength, s.MaxLength = intPtr(fr.min), intPtr(fr.max)
+		for _, e := range fr.enum {
+			s.Enum = append(s.Enum, e)
+		}
+		if fr.regex != nil {
+			s.Pattern = fr.regex.String()
+		}
+	case "array":
+		s.MinItems, s.MaxItems = intPtr(fr.min), intPtr(fr.max)
+	case "object":
+		s.MinProperties, s.MaxProperties = intPtr(fr.min), intPtr(fr.max)
+	case "integer", "number":
+		s.Minimum, s.Maximum = fr.min, fr.max
+		for _, e := range fr.enum {
+			if n, err := strconv.ParseFloat(e, 64); err == nil {
+				s.Enum = append(s.Enum, n)
+			}
+		}
+	}
+} //fieldRules.addToSchema()
+
+//RequestMessageSchema returns the schema of the RequestMessage envelope
+func RequestMessageSchema() *Schema {
+	return NewSchema("Request Message", reflect.TypeOf(RequestMessage{}))
+}
+
+//ResponseMessageSchema returns the schema of the ResponseMessage envelope
+func ResponseMessageSchema() *Schema {
+	return NewSchema("Response Message", reflect.TypeOf(ResponseMessage{}))
+}
+
+//RequestSchema returns the schema of the request data of the named operation
+//or nil if the operation does not exist
+func (msvc msvc) RequestSchema(operName string) *Schema {
+	operTmpl, ok := msvc.operTmpl[operName]
+	if !ok {
+		return nil
+	}
+	return NewSchema(msvc.name+"."+operName+" request", reflect.TypeOf(operTmpl))
+}
+
+//ResponseSchema returns the schema of the response data of the named operation
+//or nil if the operation does not exist
+func (msvc msvc) ResponseSchema(operName string) *Schema {
+	operTmpl, ok := msvc.operTmpl[operName]
+	if !ok {
+		return nil
+	}
+	return NewSchema(msvc.name+"."+operName+" response", msvc.responseType(operTmpl, operName))
+}
+
+//responseType returns the type of response data of the operation or nil if not declared.
+//Asynchronous operations respond with a Job
+func (msvc msvc) responseType(operTmpl IOper, operName string) reflect.Type {
+	if msvc.asyncOpers[operName] {
+		return reflect.TypeOf(Job{})
+	}
+	if operWithResponse, ok := operTmpl.(IOperWithResponse); ok {
+		return reflect.TypeOf(operWithResponse.Response())
+	}
+	return nil
+}
+
+//WriteSchemas writes the schema files of the message envelopes and of all
+//operations' request and response data into the specified directory:
+//
+//	request-message.schema.json
+//	response-message.schema.json
+//	<oper>.request.schema.json
+//	<oper>.response.schema.json
+func (msvc msvc) WriteSchemas(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return log.Wrapf(err, "Failed to create schema directory %s", dir)
+	}
+	schemas := map[string]*Schema{
+		"request-message.schema.json":  RequestMessageSchema(),
+		"response-message.schema.json": ResponseMessageSchema(),
+	}
+	for _, operName := range msvc.operNames() {
+		schemas[operName+".request.schema.json"] = msvc.RequestSchema(operName)
+		schemas[operName+".response.schema.json"] = msvc.ResponseSchema(operName)
+	}
+	for fileName, schema := range schemas {
+		if err := writeJSONFile(filepath.Join(dir, fileName), schema); err != nil {
+			return err
+		}
+	}
+	return nil
+} //msvc.WriteSchemas()
+
+//writeJSONFile writes the value as indented JSON into the named file
+func writeJSONFile(fileName string, v interface{}) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return log.Wrapf(err, "Failed to encode %s", fileName)
+	}
+	if err := ioutil.WriteFile(fileName, append(jsonData, '\n'), 0644); err != nil {
+		return log.Wrapf(err, "Failed to write %s", fileName)
+	}
+	return nil
+}
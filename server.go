@@ -1,6 +1,8 @@
 package msvc
 
 import (
+	"context"
+	"reflect"
 	"sync"
 
 	"github.com/jansemmelink/config"
@@ -14,7 +16,20 @@ type IServer interface {
 	config.IValidator
 
 	//Run is a blocking call that calls the micro-server Handler method for received requests
-	Run(msvc IMicroService)
+	//until ctx is cancelled, then it must stop receiving, complete requests in progress and return
+	Run(ctx context.Context, msvc IMicroService)
+}
+
+//IServerWithListen is an optional IServer interface for servers that must bind an
+//address or connect before they can receive requests, so that the framework knows
+//whether the server started before it stops the server with the old configuration
+type IServerWithListen interface {
+	IServer
+
+	//Listen binds or connects and returns the server to Run(), which receives requests
+	//from what was bound until ctx is cancelled and then releases it,
+	//or an error when the server cannot receive requests, then Run() is not called
+	Listen(msvc IMicroService) (IServer, error)
 }
 
 //RegisterServer must be called in the server implementation's init() func
@@ -39,22 +54,131 @@ var (
 	serverTmpl  = make(map[string]IServer)
 )
 
-func startConfiguredServers(wg *sync.WaitGroup, cs config.ISet, msvc IMicroService) {
+//configuredServers loads the configuration of all registered servers from the config set
+//and returns the valid servers and the errors for servers that could not be loaded
+func configuredServers(cs config.ISet) (map[string]IServer, map[string]error) {
+	serverMutex.Lock()
+	defer serverMutex.Unlock()
+
 	log.Debugf("Trying %d server configurations...", len(serverTmpl))
+	configured := make(map[string]IServer)
+	errs := make(map[string]error)
 	for serverName, tmpl := range serverTmpl {
 		serverConfiguration, err := cs.Add(serverName, tmpl)
 		if err != nil {
-			log.Debugf("server[%s] not configured: %+v", serverName, err)
+			errs[serverName] = err
 			continue
 		}
+		configured[serverName] = serverConfiguration.Current().(IServer)
+	}
+	return configured, errs
+} //configuredServers()
 
-		configuredServer := serverConfiguration.Current().(IServer)
-		log.Debugf("Got %s: %T: %+v", serverName, configuredServer, configuredServer)
+//servers are the configured servers that were started.
+//It is only used from the goroutine that serves the micro-service
+type servers struct {
+	ctx     context.Context
+	wg      *sync.WaitGroup
+	msvc    IMicroService
+	running map[string]*runningServer
+}
+
+//runningServer can be stopped without stopping the other servers
+type runningServer struct {
+	server IServer
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newServers(ctx context.Context, wg *sync.WaitGroup, msvc IMicroService) *servers {
+	return &servers{
+		ctx:     ctx,
+		wg:      wg,
+		msvc:    msvc,
+		running: make(map[string]*runningServer),
+	}
+}
+
+//start the server to call the micro-service handler when it received a request,
+//or return an error if the server could not listen
+func (s *servers) start(serverName string, configuredServer IServer) error {
+	runServer := configuredServer
+	if listener, ok := configuredServer.(IServerWithListen); ok {
+		var err error
+		runServer, err = listener.Listen(s.msvc)
+		if err != nil {
+			return log.Wrapf(err, "server[%s] failed to listen", serverName)
+		}
+	}
 
-		//start the server to call the micro-service handler when it received a request
-		wg.Add(1)
-		go configuredServer.Run(msvc)
+	ctx, cancel := context.WithCancel(s.ctx)
+	rs := &runningServer{
+		server: configuredServer,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.running[serverName] = rs
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(rs.done)
+		runServer.Run(ctx, s.msvc)
+	}()
+	log.Debugf("Started server %s (%T)%+v", serverName, configuredServer, configuredServer)
+	return nil
+}
+
+//stop the running server and wait for it to complete the requests in progress
+func (s *servers) stop(serverName string, rs *runningServer) {
+	rs.cancel()
+	<-rs.done
+	log.Debugf("Stopped server %s (%T)%+v", serverName, rs.server, rs.server)
+}
+
+//restart the server with a new configuration. The new server is started before the
+//old one is stopped, so the old one keeps running when the new one cannot listen,
+//e.g. because its address is in use.
+func (s *servers) restart(serverName string, configuredServer IServer) {
+	old := s.running[serverName]
+	if err := s.start(serverName, configuredServer); err != nil {
+		log.Errorf("server[%s] keeps running with old config, new server rejected: %+v", serverName, err)
+		return
+	}
+	s.stop(serverName, old)
+}
 
-		log.Debugf("Started server (%T)%+v", configuredServer, configuredServer)
+//update starts servers that are configured but not running, restarts servers
+//when their configuration changed and stops servers when their configuration was
+//removed. A running server keeps running with its old configuration when the new
+//configuration could not be loaded.
+func (s *servers) update(configured map[string]IServer, errs map[string]error) {
+	for serverName, err := range errs {
+		rs, ok := s.running[serverName]
+		if !ok {
+			log.Debugf("server[%s] not configured: %+v", serverName, err)
+			continue
+		}
+		if !configExists(serverName) {
+			log.Debugf("server[%s] config removed", serverName)
+			s.stop(serverName, rs)
+			delete(s.running, serverName)
+			continue
+		}
+		log.Errorf("server[%s] keeps running with old config, new config rejected: %+v", serverName, err)
+	}
+
+	for serverName, configuredServer := range configured {
+		log.Debugf("Got %s: %T: %+v", serverName, configuredServer, configuredServer)
+		rs, ok := s.running[serverName]
+		if !ok {
+			if err := s.start(serverName, configuredServer); err != nil {
+				log.Errorf("%+v", err)
+			}
+			continue
+		}
+		if reflect.DeepEqual(rs.server, configuredServer) {
+			continue
+		}
+		s.restart(serverName, configuredServer)
 	}
-} //startConfiguredServers()
+} //servers.update()
@@ -0,0 +1,47 @@
+//Package httpserver is used by the HTTP msvc.IServer implementations
+//to serve requests and shut down gracefully when they are stopped
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jansemmelink/log"
+)
+
+//ShutdownTimeout is how long the HTTP server waits for connections to close when it stops
+const ShutdownTimeout = 5 * time.Second
+
+//Serve the handler on the listener until ctx is cancelled, then shut down the
+//HTTP server and return after the requests in progress completed or ShutdownTimeout.
+//The name is used in the logs, e.g. "HTTP server localhost:12345"
+func Serve(ctx context.Context, name string, listener net.Listener, handler http.Handler) {
+	httpServer := &http.Server{
+		Addr:    listener.Addr().String(),
+		Handler: handler,
+	}
+
+	//shutdown the HTTP server when the context is cancelled
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("%s shutdown failed: %+v", name, err)
+		}
+	}()
+
+	if err := httpServer.Serve(listener); err != http.ErrServerClosed {
+		log.Errorf("%s failed: %+v", name, err)
+		return
+	}
+
+	//Serve() returns immediately when Shutdown() is called,
+	//so wait for shutdown to complete
+	<-stopped
+	log.Debugf("%s stopped", name)
+} //Serve()
@@ -0,0 +1,99 @@
+//Package loopback implements a msvc.IServer that receives requests in memory
+//from msvc/client/loopback clients in the same process, without sockets.
+//Requests and responses are encoded and decoded as JSON like the network servers.
+//
+//Enable it with conf/loopback.json:
+//
+//	{}
+//
+//or, in tests, call Attach() without serving the micro-service:
+//
+//	detach := loopback.Attach(svc)
+//	defer detach()
+package loopback
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+)
+
+//loopbackServer implements msvc.IServer
+type loopbackServer struct{}
+
+func (ls loopbackServer) Validate() error {
+	return nil
+}
+
+//Run makes the micro-service available to loopback clients until ctx is done
+func (ls loopbackServer) Run(ctx context.Context, service msvc.IMicroService) {
+	detach := Attach(service)
+	defer detach()
+	log.Debugf("Loopback server %s started", service.Name())
+	<-ctx.Done()
+	log.Debugf("Loopback server %s stopped", service.Name())
+}
+
+var (
+	servicesMutex sync.RWMutex
+	services      = make(map[string]msvc.IMicroService)
+)
+
+//Attach makes the micro-service available to loopback clients
+//and returns the func to call to make it unavailable again
+func Attach(service msvc.IMicroService) func() {
+	servicesMutex.Lock()
+	defer servicesMutex.Unlock()
+	if _, ok := services[service.Name()]; ok {
+		panic(log.Wrapf(nil, "Loopback service %s already attached", service.Name()))
+	}
+	services[service.Name()] = service
+	return func() {
+		servicesMutex.Lock()
+		defer servicesMutex.Unlock()
+		delete(services, service.Name())
+	}
+}
+
+//Send passes the JSON request message to the named service
+//and returns the JSON response message, just like a network server
+func Send(ctx context.Context, serviceName string, operName string, jsonRequestMessage []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, log.Wrapf(err, "Loopback request to %s.%s not sent", serviceName, operName)
+	}
+	servicesMutex.RLock()
+	service, ok := services[serviceName]
+	servicesMutex.RUnlock()
+	if !ok {
+		return nil, log.Wrapf(nil, "Loopback service %s not attached", serviceName)
+	}
+
+	//handle in the background so the caller can give up when ctx is done
+	response := make(chan interface{}, 1)
+	go func() {
+		if operName == msvc.BatchOperName {
+			response <- service.HandleBatchJSON(jsonRequestMessage)
+			return
+		}
+		responseMessage := service.HandleJSON(operName, jsonRequestMessage)
+		msvc.NewLogger(responseMessage.Header.UUID).Debugf("Loopback %s.%s", serviceName, operName)
+		response <- responseMessage
+	}()
+	select {
+	case responseMessage := <-response:
+		jsonResponseMessage, err := json.Marshal(responseMessage)
+		if err != nil {
+			return nil, log.Wrapf(err, "Failed to encode response message from %s.%s", serviceName, operName)
+		}
+		return jsonResponseMessage, nil
+	case <-ctx.Done():
+		return nil, log.Wrapf(ctx.Err(), "Loopback request to %s.%s not completed", serviceName, operName)
+	}
+} //Send()
+
+func init() {
+	msvc.RegisterServer("loopback", loopbackServer{})
+}
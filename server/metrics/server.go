@@ -0,0 +1,73 @@
+//Package metrics implements a msvc.IServer that exposes the micro-service metrics
+//over HTTP in the Prometheus text exposition format, e.g. with conf/metrics.json:
+//
+//	{"address":"localhost:9102"}
+//
+//the metrics are available at http://localhost:9102/metrics
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/msvc"
+	"github.com/jansemmelink/msvc/server/httpserver"
+)
+
+//metricsServer implements msvc.IServer to serve the metrics of the micro-service
+type metricsServer struct {
+	Address string `json:"address" doc:"HTTP Server address, e.g. localhost:9102"`
+	Path    string `json:"path" doc:"HTTP path of the metrics. Defaults to \"/metrics\""`
+
+	//run-time private data:
+	msvc     msvc.IMicroService
+	listener net.Listener
+}
+
+func (ms *metricsServer) Validate() error {
+	if len(ms.Address) == 0 {
+		return log.Wrapf(nil, "Missing address")
+	}
+	if len(ms.Path) == 0 {
+		ms.Path = "/metrics"
+	}
+	log.Debugf("Validated %T", ms)
+	return nil
+}
+
+//Listen binds the address so that the server is known to start before Run().
+//It sets the listener in a copy, so the configured server can still be compared to a new configuration
+func (ms metricsServer) Listen(msvc msvc.IMicroService) (msvc.IServer, error) {
+	listener, err := net.Listen("tcp", ms.Address)
+	if err != nil {
+		return nil, log.Wrapf(err, "Metrics server cannot listen on %s", ms.Address)
+	}
+	ms.listener = listener
+	return &ms, nil
+}
+
+func (ms metricsServer) Run(ctx context.Context, msvc msvc.IMicroService) {
+	ms.msvc = msvc
+	mux := http.NewServeMux()
+	mux.Handle(ms.Path, ms)
+	httpserver.Serve(ctx, "Metrics server "+ms.Address, ms.listener, mux)
+} //metricsServer.Run()
+
+func (ms metricsServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := ms.msvc.WriteMetrics(res); err != nil {
+		log.Errorf("Failed to write metrics: %+v", err)
+	}
+}
+
+func init() {
+	//register &<struct>{} so that Validate() method will be called with pointer receiver
+	//and be able to set defaults
+	msvc.RegisterServer("metrics", &metricsServer{})
+}
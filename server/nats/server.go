@@ -3,21 +3,23 @@
 //and you send requests to that topic to have them served,
 //for example by using the github.com:nats.io/examples/nats-req utility like this:
 //
-//  $ nats-req template.hello '{"request":{"value":123}}'
-//	Published [template.hello] : '{"request":{"value":123}}'
-//	Received  [_INBOX.C07XQjpNIGccsfAU3QDW6c.aSkIsJFD] : '{"header":{...}, "request":{...}, "result":{...}, "response":{...}}'
+//	 $ nats-req template.hello '{"request":{"value":123}}'
+//		Published [template.hello] : '{"request":{"value":123}}'
+//		Received  [_INBOX.C07XQjpNIGccsfAU3QDW6c.aSkIsJFD] : '{"header":{...}, "request":{...}, "result":{...}, "response":{...}}'
 //
 //One can also submit a header with constraints in the request, often with timeout value.
 //When you receive will contain optional items for header, request, result and response.
 package nats
 
 import (
-	"time"
-	"strings"
+	"context"
 	"encoding/json"
-	"github.com/nats-io/nats.go"
+	"strings"
+	"time"
+
 	"github.com/jansemmelink/log"
 	"github.com/jansemmelink/msvc"
+	"github.com/nats-io/nats.go"
 )
 
 //nats implements msvc.IServer to server micro-services from a NATS topic
@@ -25,7 +27,10 @@ type natsServer struct {
 	URL string `json:"url" doc:"URL of NATS server. Defaults to \"localhost:4222\""`
 
 	//run-time private data:
-	msvc msvc.IMicroService
+	msvc         msvc.IMicroService
+	conn         *nats.Conn
+	subscription *nats.Subscription
+	closed       chan struct{}
 }
 
 func (ns *natsServer) Validate() error {
@@ -36,63 +41,99 @@ func (ns *natsServer) Validate() error {
 	return nil
 }
 
-func (ns natsServer) Run(msvc msvc.IMicroService) {
+//Listen connects and subscribes so that the server is known to start before Run().
+//It sets the connection in a copy, so the configured server can still be compared to a new configuration
+func (ns natsServer) Listen(msvc msvc.IMicroService) (msvc.IServer, error) {
 	ns.msvc = msvc
 
 	//connect to NATS
+	closed := make(chan struct{})
 	conn, err := nats.Connect(ns.URL,
 		nats.MaxReconnects(-1),
 		nats.ReconnectWait(time.Second*2),
 		nats.ReconnectHandler(func(conn *nats.Conn) {
 			log.Debugf("Trying to reconnect %+v\n", conn)
+		}),
+		nats.ClosedHandler(func(conn *nats.Conn) {
+			close(closed)
 		}))
 	if err != nil {
-		panic(log.Wrapf(err, "Failed to connect to NATS server %s", ns.URL))
+		return nil, log.Wrapf(err, "Failed to connect to NATS server %s", ns.URL)
 	}
 
 	//make a queue subscription to start consuming messages from the topic
-	/*subscription*/_, err = conn.QueueSubscribe(
+	subscription, err := conn.QueueSubscribe(
 		msvc.Name()+".*",
 		"Q"+msvc.Name(),
 		func(msg *nats.Msg) {
-			log.Debugf("NATS %s", msg.Subject)
 			ns.handleMessage(conn, msg)
 		})
 	if err != nil {
-		panic(log.Wrapf(err, "NATS Queue Subscription failed."))
+		conn.Close()
+		return nil, log.Wrapf(err, "NATS Queue Subscription failed.")
 	}
+	ns.conn = conn
+	ns.subscription = subscription
+	ns.closed = closed
+	return &ns, nil
+} //natsServer.Listen()
 
-	//handler.defaultReplyQ = subject+".reply"
-	//return nil
+func (ns natsServer) Run(ctx context.Context, msvc msvc.IMicroService) {
+	//subscribed in Listen(), now block until stopped
+	<-ctx.Done()
 
-	//subscribed successfully, now
-	//(todo) block until subscription terminated
-	//(for now block for ever)
-	ch := make(chan bool)
-	<- ch
+	//stop receiving, but process messages already received,
+	//then drain the connection so replies are flushed before it is closed
+	if err := ns.subscription.Drain(); err != nil {
+		log.Errorf("Failed to drain NATS subscription: %+v", err)
+	}
+	if err := ns.conn.Drain(); err != nil {
+		log.Errorf("Failed to drain NATS connection: %+v", err)
+		ns.conn.Close()
+	}
+	<-ns.closed
+	log.Debugf("NATS subscription %s.* stopped", msvc.Name())
 } //natsServer.Run()
 
 func (ns natsServer) handleMessage(conn *nats.Conn, msg *nats.Msg) {
-	log.Debugf("Received: %s", string(msg.Data))
+	operName := operNameFromSubject(msg.Subject)
+	if operName == msvc.BatchOperName {
+		ns.handleBatch(conn, msg)
+		return
+	}
 
 	//execute the operation
-	responseMessage := ns.msvc.HandleJSON(operNameFromSubject(msg.Subject), msg.Data)
-	jsonResponseMessage,_ := json.Marshal(responseMessage)
+	responseMessage := ns.msvc.HandleJSON(operName, msg.Data)
+	jsonResponseMessage, _ := json.Marshal(responseMessage)
 
+	//log with the request uuid from the response header
+	logger := msvc.NewLogger(responseMessage.Header.UUID)
+	logger.Debugf("NATS %s", msg.Subject)
+	logger.Debugf("Received: %s", string(msg.Data))
 	if err := conn.Publish(msg.Reply, jsonResponseMessage); err != nil {
-		log.Errorf("Failed to reply to \"%s\": %+v", msg.Reply, err)
-	}/* else {
+		logger.Errorf("Failed to reply to \"%s\": %+v", msg.Reply, err)
+	} /* else {
 		log.Debugf("Replied to: \"%s\"", msg.Reply)
 	}*/
 }
 
+//handleBatch replies with the list of response messages to a batch request
+func (ns natsServer) handleBatch(conn *nats.Conn, msg *nats.Msg) {
+	batchResponse := ns.msvc.HandleBatchJSON(msg.Data)
+	jsonBatchResponse, _ := json.Marshal(batchResponse)
+	log.Debugf("NATS %s (%d items)", msg.Subject, len(batchResponse.Items))
+	if err := conn.Publish(msg.Reply, jsonBatchResponse); err != nil {
+		log.Errorf("Failed to reply to \"%s\": %+v", msg.Reply, err)
+	}
+}
+
 func operNameFromSubject(subject string) string {
 	parts := strings.SplitN(subject, ".", 2)
 	if len(parts) == 2 {
 		return parts[1]
 	}
 	return ""
-}//operNameFromSubject()
+} //operNameFromSubject()
 
 func init() {
 	//register &<struct>{} so that Validate() method will be called with pointer receiver
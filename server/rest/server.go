@@ -1,14 +1,19 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/jansemmelink/log"
 	"github.com/jansemmelink/msvc"
+	"github.com/jansemmelink/msvc/server/httpserver"
 )
 
 //restServer implements msvc.IServer to be a HTTP REST interface for micro-services
@@ -16,7 +21,8 @@ type restServer struct {
 	Address string `json:"address" doc:"HTTP Server address, e.g. localhost:12345"`
 
 	//run-time private data:
-	msvc msvc.IMicroService
+	msvc     msvc.IMicroService
+	listener net.Listener
 }
 
 func (rs restServer) Validate() error {
@@ -27,17 +33,29 @@ func (rs restServer) Validate() error {
 	return nil
 }
 
-func (rs restServer) Run(msvc msvc.IMicroService) {
-	rs.msvc = msvc
-	http.ListenAndServe(rs.Address, rs)
+//Listen binds the address so that the server is known to start before Run()
+func (rs restServer) Listen(msvc msvc.IMicroService) (msvc.IServer, error) {
+	listener, err := net.Listen("tcp", rs.Address)
+	if err != nil {
+		return nil, log.Wrapf(err, "HTTP server cannot listen on %s", rs.Address)
+	}
+	rs.listener = listener
+	return rs, nil
 }
 
+func (rs restServer) Run(ctx context.Context, msvc msvc.IMicroService) {
+	rs.msvc = msvc
+	httpserver.Serve(ctx, "HTTP server "+rs.Address, rs.listener, rs)
+} //restServer.Run()
+
 func (rs restServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	log.Debugf("HTTP %s %s", req.Method, req.URL)
+	if req.Method == http.MethodGet && req.URL.Path == msvc.OpenAPIPath {
+		rs.serveOpenAPI(res, req)
+		return
+	}
 
 	//read request into byte buffer
 	jsonRequestData := read(req.Body)
-	log.Debugf("Request: %s", string(jsonRequestData))
 
 	// var v interface{}
 	// var jsonResponseMessage []byte
@@ -49,10 +67,66 @@ func (rs restServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	// 		},
 	// 	})
 	// } else {
-	responseMessage := rs.msvc.HandleJSON(operNameFromURL(req.URL), jsonRequestData)
+	operName := operNameFromURL(req.URL)
+	if operName == msvc.BatchOperName {
+		rs.serveBatch(res, req, jsonRequestData)
+		return
+	}
+	responseMessage := rs.msvc.HandleJSON(operName, jsonRequestData)
 	jsonResponseMessage, _ := json.Marshal(responseMessage)
 	// }
+	httpStatus := rs.httpStatus(operName, responseMessage)
+	res.Header().Set("Content-Type", "application/json")
+	if responseMessage.Error != nil && responseMessage.Error.RetryAfter > 0 {
+		//Retry-After is in whole seconds, rounded up so the consumer does not retry too soon
+		res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(responseMessage.Error.RetryAfter.Seconds()))))
+	}
+	res.WriteHeader(httpStatus)
 	res.Write(jsonResponseMessage)
+
+	//log with the request uuid from the response header
+	logger := msvc.NewLogger(responseMessage.Header.UUID)
+	logger.Debugf("HTTP %s %s -> %d", req.Method, req.URL, httpStatus)
+	logger.Debugf("Request: %s", string(jsonRequestData))
+	logger.Debugf("Response: %s", string(jsonResponseMessage))
+}
+
+//serveBatch responds with the list of response messages to a batch request.
+//The HTTP status is OK even when items failed, as each item has its own error
+func (rs restServer) serveBatch(res http.ResponseWriter, req *http.Request, jsonBatchRequest []byte) {
+	batchResponse := rs.msvc.HandleBatchJSON(jsonBatchRequest)
+	jsonBatchResponse, _ := json.Marshal(batchResponse)
+	httpStatus := http.StatusOK
+	if batchResponse.Error != nil {
+		httpStatus = http.StatusBadRequest
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(httpStatus)
+	res.Write(jsonBatchResponse)
+	log.Debugf("HTTP %s %s (%d items) -> %d", req.Method, req.URL, len(batchResponse.Items), httpStatus)
+}
+
+//httpStatus returns the HTTP status declared for the result in the response message
+func (rs restServer) httpStatus(operName string, responseMessage msvc.ResponseMessage) int {
+	if responseMessage.Error == nil {
+		return http.StatusOK
+	}
+	if result := rs.msvc.Result(operName, responseMessage.Error.Type); result != nil && result.HTTPStatus() != 0 {
+		return result.HTTPStatus()
+	}
+	return http.StatusInternalServerError
+} //restServer.httpStatus()
+
+//serveOpenAPI responds with the OpenAPI document describing this server
+func (rs restServer) serveOpenAPI(res http.ResponseWriter, req *http.Request) {
+	jsonDoc, err := json.Marshal(rs.msvc.OpenAPI("http://" + req.Host))
+	if err != nil {
+		log.Errorf("Failed to encode OpenAPI document: %+v", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(jsonDoc)
 }
 
 func operNameFromURL(url *url.URL) string {
@@ -0,0 +1,63 @@
+package msvc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+//testServer runs until stopped and fails to listen when Fail is set
+type testServer struct {
+	Version int
+	Fail    bool
+}
+
+func (ts testServer) Validate() error { return nil }
+
+func (ts testServer) Listen(msvc IMicroService) (IServer, error) {
+	if ts.Fail {
+		return nil, errors.New("address in use")
+	}
+	return ts, nil
+}
+
+func (ts testServer) Run(ctx context.Context, msvc IMicroService) {
+	<-ctx.Done()
+}
+
+func TestServersRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wg := sync.WaitGroup{}
+	s := newServers(ctx, &wg, nil)
+	s.update(map[string]IServer{"test": testServer{Version: 1}}, nil)
+	rs := s.running["test"]
+
+	//the old server keeps running when the new one cannot listen
+	s.update(map[string]IServer{"test": testServer{Version: 2, Fail: true}}, nil)
+	if s.running["test"] != rs {
+		t.Fatalf("Expected the old server to keep running")
+	}
+	select {
+	case <-rs.done:
+		t.Fatalf("Expected the old server not to be stopped")
+	default:
+	}
+
+	//the old server is stopped after the new one started
+	s.update(map[string]IServer{"test": testServer{Version: 2}}, nil)
+	if s.running["test"] == rs {
+		t.Fatalf("Expected the new server to run")
+	}
+	<-rs.done
+
+	//a server is stopped when its configuration is removed
+	rs = s.running["test"]
+	s.update(nil, map[string]error{"test": errors.New("not found")})
+	if _, ok := s.running["test"]; ok {
+		t.Fatalf("Expected the removed server to be stopped")
+	}
+	<-rs.done
+	wg.Wait()
+}
@@ -0,0 +1,115 @@
+package msvc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//defaultDrainTimeout is how long Serve() waits for requests in progress
+//to complete after the service started to terminate
+const defaultDrainTimeout = 10 * time.Second
+
+//lifecycle tracks the requests in progress so that the service can drain them
+//before it terminates, and rejects new requests once the service is terminating
+type lifecycle struct {
+	mutex       sync.Mutex
+	terminating bool
+	inProgress  sync.WaitGroup
+}
+
+//begin must be called when a request is received and returns false
+//if the request must be rejected because the service is terminating.
+//When it returns true, end() must be called when the request completed.
+func (l *lifecycle) begin() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.terminating {
+		return false
+	}
+	l.inProgress.Add(1)
+	return true
+}
+
+func (l *lifecycle) end() {
+	l.inProgress.Done()
+}
+
+//terminate makes begin() reject all subsequent requests
+func (l *lifecycle) terminate() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.terminating = true
+}
+
+//drain waits for all requests in progress to complete
+//and returns false if they did not complete within the timeout
+func (l *lifecycle) drain(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		l.inProgress.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+} //lifecycle.drain()
+
+//requestSlots holds the lifecycle and concurrency slots of a request until they are released.
+//HandleJSON() releases them when it returns, unless they were taken by a goroutine that still
+//uses the oper after HandleJSON() returned (see msvc.run() and msvc.startJob())
+type requestSlots struct {
+	release []func()
+	//owner is changed atomically, as the slots may be taken in another goroutine
+	owner int32
+}
+
+//owners of the request slots
+const (
+	slotsHeld     int32 = iota //held by HandleJSON()
+	slotsTaken                 //taken by a goroutine that calls the func returned by take()
+	slotsReleased              //released when HandleJSON() returned
+)
+
+//add a func to call when the slots are released
+func (rs *requestSlots) add(release func()) {
+	rs.release = append(rs.release, release)
+}
+
+//take makes the caller responsible to call the returned func to release the slots.
+//When HandleJSON() already released the slots, the returned func does nothing
+func (rs *requestSlots) take() func() {
+	if rs == nil || !atomic.CompareAndSwapInt32(&rs.owner, slotsHeld, slotsTaken) {
+		return func() {}
+	}
+	return rs.releaseAll
+}
+
+//taken is true when the slots were taken by another goroutine
+func (rs *requestSlots) taken() bool {
+	return atomic.LoadInt32(&rs.owner) == slotsTaken
+}
+
+//end is called when HandleJSON() returns to release the slots unless they were taken
+func (rs *requestSlots) end() {
+	if atomic.CompareAndSwapInt32(&rs.owner, slotsHeld, slotsReleased) {
+		rs.releaseAll()
+	}
+}
+
+//releaseAll in the reverse order they were added
+func (rs *requestSlots) releaseAll() {
+	for i := len(rs.release) - 1; i >= 0; i-- {
+		rs.release[i]()
+	}
+}
+
+//slotsFromContext returns the slots of the request or nil if the context was not created for a request
+func slotsFromContext(ctx context.Context) *requestSlots {
+	slots, _ := ctx.Value(contextKeySlots).(*requestSlots)
+	return slots
+}
@@ -2,13 +2,20 @@
 package main
 
 import (
+	"flag"
+
 	//other libraries
 	"github.com/jansemmelink/log"
 
 	//config sources that may be used:
 	_ "github.com/jansemmelink/config/source/files"
 
+	//audit sinks that may be used:
+	_ "github.com/jansemmelink/msvc/audit/file"
+	_ "github.com/jansemmelink/msvc/audit/nats"
+
 	//micro-server server implementations that may be used:
+	_ "github.com/jansemmelink/msvc/server/metrics"
 	_ "github.com/jansemmelink/msvc/server/nats"
 	_ "github.com/jansemmelink/msvc/server/rest"
 )
@@ -20,8 +27,30 @@ func main() {
 	//create the micro-service definition
 	t := Template()
 
+	//optionally write the JSON schemas or OpenAPI document and exit
+	schemaDir := flag.String("schema", "", "Write JSON schemas of all operations into this directory and exit")
+	openAPIFile := flag.String("openapi", "", "Write the OpenAPI document of the rest interface into this file and exit")
+	flag.Parse()
+	if len(*schemaDir) > 0 {
+		if err := t.WriteSchemas(*schemaDir); err != nil {
+			panic(log.Wrapf(err, "Failed to write schemas"))
+		}
+		return
+	}
+	if len(*openAPIFile) > 0 {
+		if err := t.WriteOpenAPI(*openAPIFile); err != nil {
+			panic(log.Wrapf(err, "Failed to write OpenAPI document"))
+		}
+		return
+	}
+
 	//not necessary - just to demonstrate
-	t.Test("hello", "{\"name\":\"Jan\"}")
+	var greeting string
+	if _, err := t.Test("hello", `{"name":"Jan"}`, &greeting); err != nil {
+		log.Errorf("Test failed: %+v", err)
+	} else {
+		log.Debugf("Test response: %s", greeting)
+	}
 
 	//serve on all configured interfaces
 	t.Serve()
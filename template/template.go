@@ -1,38 +1,62 @@
 package main
 
 import (
-	"github.com/jansemmelink/log"
+	"github.com/jansemmelink/config"
 	"github.com/jansemmelink/msvc"
 )
 
 //Template create the micro-service with several operations to demonstrate how the framework is used
 func Template() msvc.IMicroService {
 	return msvc.New("template").
+		WithResource("greeting", "Hi").
 		WithOper("hello", hello{})
 }
 
 //hello operation implements msvc.IOper
 type hello struct {
 	msvc.Oper
-	Name string `json:"name"`
+	Name string `json:"name" doc:"Name of the person to greet." validate:"required,max=50"`
+
+	//resources injected by the framework:
+	Greeting string `json:"-" inject:"greeting"`
+}
+
+//hello needs no Validate() method: the validate tags are applied and msvc.Oper.Validate() does the rest
+
+func (h hello) Results() []msvc.IResult {
+	//hello only fails on invalidRequest which is a framework result
+	return nil
 }
 
-func (h hello) Validate() error {
-	if len(h.Name) == 0 {
-		log.Debugf("Invalid hello: %+v", h)
-		return log.Wrapf(nil, "missing name")
+//ConfigTmpl declares that hello is configured in ./conf/template-hello.json
+func (h hello) ConfigTmpl() config.IValidator {
+	return &helloConfig{}
+}
+
+//helloConfig is the configuration of the hello operation
+type helloConfig struct {
+	Suffix string `json:"suffix"`
+}
+
+//Validate sets the default suffix
+func (c *helloConfig) Validate() error {
+	if c.Suffix == "" {
+		c.Suffix = "!"
 	}
-	log.Debugf("Valid hello: %+v", h)
 	return nil
 }
 
-func (h hello) Results() []msvc.IResult {
-	panic("NYI")
-	//return nil
+//Response declares that hello responds with a string
+func (h hello) Response() interface{} {
+	return ""
 }
 
 func (h hello) Run() (interface{}, *msvc.Error) { //Run() (msvc.IResult, interface{}) {
-	log.Debugf("Hello: %+v", h)
-	return "Hi " + h.Name, nil
+	h.Log().Debugf("Hello: %+v", h)
+	greeting := h.Greeting + " " + h.Name
+	if c, ok := h.Config().(*helloConfig); ok {
+		greeting += c.Suffix
+	}
+	return greeting, nil
 	//return nil, &msvc.Error{Type: "NYI"}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jansemmelink/msvc"
+	"github.com/jansemmelink/msvc/msvctest"
+)
+
+func TestHello(t *testing.T) {
+	svc := Template()
+	if err := svc.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	var greeting string
+	msvctest.MustSucceed(t, svc, "hello", map[string]string{"name": "Jan"}, &greeting)
+	if greeting != "Hi Jan!" {
+		t.Fatalf("Expected \"Hi Jan!\" but got \"%s\"", greeting)
+	}
+
+	e := msvctest.MustFail(t, svc, "hello", map[string]string{}, "invalidRequest")
+	msvctest.AssertFieldError(t, e, "name", "required")
+}
+
+//notFoundOper returns an error type that it does not declare in its results
+type notFoundOper struct {
+	msvc.Oper
+}
+
+func (o notFoundOper) Results() []msvc.IResult { return nil }
+
+func (o notFoundOper) Run() (interface{}, *msvc.Error) {
+	return nil, &msvc.Error{Type: "notFound", Description: "not declared"}
+}
+
+func TestUndeclaredResult(t *testing.T) {
+	svc := Template().WithOper("find", notFoundOper{})
+	msvctest.MustFail(t, svc, "find", nil, "undeclaredResult")
+}
+
+//TestFixtures runs the cases in ./fixtures, use go test -update-fixtures to write the expected responses
+func TestFixtures(t *testing.T) {
+	svc := Template()
+	if err := svc.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	msvctest.RunFixtures(t, svc, "fixtures")
+}
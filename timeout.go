@@ -0,0 +1,108 @@
+package msvc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jansemmelink/log"
+)
+
+//WithOperTimeout sets the max time the named operation may run when the request
+//did not specify a shorter max-duration. When exceeded, the consumer gets a "timeout"
+//error and the oper context is cancelled to signal the oper to stop
+func (msvc msvc) WithOperTimeout(operName string, timeout time.Duration) IMicroService {
+	if _, ok := msvc.operTmpl[operName]; !ok {
+		panic(log.Wrapf(nil, "MicroService[%s].oper[%s] does not exist", msvc.name, operName))
+	}
+	msvc.operTimeouts[operName] = timeout
+	return msvc
+}
+
+//LateResults returns the nr of results of the named operation that were
+//discarded because the oper completed after the consumer got a timeout
+func (msvc msvc) LateResults(operName string) int {
+	return msvc.lateResults.get(operName)
+}
+
+//operRun is the state of one oper running in its own goroutine
+type operRun struct {
+	mutex     sync.Mutex
+	finished  bool
+	abandoned bool
+	response  ResponseMessage
+	//release the request slots when an abandoned oper returns
+	release func()
+}
+
+//run is the handler at the end of the middleware chain.
+//It runs the oper in a goroutine (see msvc.runOper()) and returns when the oper
+//completed or when the request context is done, whichever comes first.
+//The request keeps its concurrency and lifecycle slots until the oper returned
+func (msvc msvc) run(ctx context.Context, request Request) ResponseMessage {
+	operName := request.OperName
+	logger := LoggerFromContext(ctx)
+	setOperContext(reflect.ValueOf(request.Oper), ctx)
+	if msvc.asyncOpers[operName] {
+		return msvc.startJob(ctx, request)
+	}
+
+	state := &operRun{}
+	done := make(chan struct{})
+	go func() {
+		var response ResponseMessage
+		defer func() {
+			//panics in the goroutine are not recovered by HandleJSON()
+			if r := recover(); r != nil {
+				response = msvc.recovered(operName, UUIDFromContext(ctx), r)
+			}
+			state.mutex.Lock()
+			defer state.mutex.Unlock()
+			if state.abandoned {
+				defer state.release()
+				msvc.lateResults.inc(operName)
+				logger.Errorf("MicroService[%s].oper[%s] completed after timeout, discarded result: %+v", msvc.name, operName, response)
+				return
+			}
+			state.finished = true
+			state.response = response
+			close(done)
+		}()
+		response = msvc.runOper(ctx, request)
+	}()
+
+	select {
+	case <-done:
+		return state.response
+	case <-ctx.Done():
+	}
+
+	//the oper may have finished at the same time
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.finished {
+		return state.response
+	}
+	state.abandoned = true
+	state.release = slotsFromContext(ctx).take()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		deadline, _ := ctx.Deadline()
+		logger.Errorf("MicroService[%s].oper[%s] timeout at %v", msvc.name, operName, deadline)
+		return ResponseMessage{
+			Error: &Error{
+				Type:        "timeout",
+				Description: fmt.Sprintf("Operation did not complete before %s", deadline.Format(TimestampFormat)),
+			},
+		}
+	}
+	logger.Errorf("MicroService[%s].oper[%s] cancelled: %v", msvc.name, operName, ctx.Err())
+	return ResponseMessage{
+		Error: &Error{
+			Type:        "terminating",
+			Description: "Operation cancelled because the micro-service is terminating",
+		},
+	}
+} //msvc.run()
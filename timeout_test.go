@@ -0,0 +1,142 @@
+package msvc_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jansemmelink/msvc"
+)
+
+func TestTimeoutKeepsSlotUntilRunReturns(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithOper("block", blockOper{}).
+		WithOperTimeout("block", 20*time.Millisecond).
+		WithOperConcurrencyLimit("block", msvc.ConcurrencyLimit{MaxConcurrent: 1})
+
+	response := svc.HandleJSON("block", []byte(`{}`))
+	if response.Error == nil || response.Error.Type != "timeout" {
+		t.Fatalf("Expected timeout but got %+v", response.Error)
+	}
+	if inFlight, _ := svc.Concurrency("block"); inFlight != 1 {
+		t.Fatalf("Expected the abandoned oper to hold its slot, got %d in flight", inFlight)
+	}
+
+	//more requests are rejected while the abandoned oper still runs
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response := svc.HandleJSON("block", []byte(`{}`))
+			if response.Error == nil || response.Error.Type != "overloaded" {
+				t.Errorf("Expected overloaded but got %+v", response.Error)
+			}
+		}()
+	}
+	wg.Wait()
+
+	//the slot is released when the oper returns
+	close(release)
+	waitFor(t, "the slot to be released", func() bool {
+		inFlight, _ := svc.Concurrency("block")
+		return inFlight == 0
+	})
+	waitFor(t, "the late result", func() bool {
+		return svc.LateResults("block") == 1
+	})
+}
+
+func TestTimeoutEchoesRequestCopy(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithOper("block", blockOper{}).
+		WithOperTimeout("block", 20*time.Millisecond)
+	response := svc.HandleJSON("block", []byte(`{"header":{"timestamp":"`+time.Now().Format(msvc.TimestampFormat)+`","echo-request":true},"request":{"name":"x"}}`))
+	if response.Error == nil || response.Error.Type != "timeout" {
+		t.Fatalf("Expected timeout but got %+v", response.Error)
+	}
+	if jsonRequest, _ := json.Marshal(response.Request); string(jsonRequest) != `{"name":"x"}` {
+		t.Fatalf("Expected the request to be echoed, got %s", jsonRequest)
+	}
+
+	//the oper may complete while the echoed request is used
+	close(release)
+	waitFor(t, "the late result", func() bool {
+		return svc.LateResults("block") == 1
+	})
+}
+
+func TestTimeoutInQueue(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithOper("block", blockOper{}).
+		WithOperTimeout("block", 50*time.Millisecond).
+		WithOperConcurrencyLimit("block", msvc.ConcurrencyLimit{MaxConcurrent: 1, MaxQueued: 1})
+	go svc.HandleJSON("block", []byte(`{}`))
+	waitFor(t, "the first request to run", func() bool {
+		inFlight, _ := svc.Concurrency("block")
+		return inFlight == 1
+	})
+
+	//the second request waits in the queue until it expires
+	queued := make(chan msvc.ResponseMessage, 1)
+	go func() {
+		queued <- svc.HandleJSON("block", []byte(`{}`))
+	}()
+	waitFor(t, "the second request to be queued", func() bool {
+		_, queued := svc.Concurrency("block")
+		return queued == 1
+	})
+
+	//the third request is rejected because the queue is full
+	if response := svc.HandleJSON("block", []byte(`{}`)); response.Error == nil || response.Error.Type != "overloaded" {
+		t.Fatalf("Expected overloaded but got %+v", response.Error)
+	}
+	if response := <-queued; response.Error == nil || response.Error.Type != "timeout" {
+		t.Fatalf("Expected timeout but got %+v", response.Error)
+	}
+
+	close(release)
+	waitFor(t, "the slot to be released", func() bool {
+		inFlight, _ := svc.Concurrency("block")
+		return inFlight == 0
+	})
+}
+
+func TestTimeoutInMiddlewareGoroutine(t *testing.T) {
+	release := make(chan struct{})
+	svc := msvc.New("test").
+		WithResource("release", release).
+		WithOper("block", blockOper{}).
+		WithOperTimeout("block", 20*time.Millisecond).
+		WithOperConcurrencyLimit("block", msvc.ConcurrencyLimit{MaxConcurrent: 1}).
+		WithMiddleware(func(next msvc.Handler) msvc.Handler {
+			//calls next in another goroutine, but before it returns
+			return func(ctx context.Context, request msvc.Request) msvc.ResponseMessage {
+				response := make(chan msvc.ResponseMessage)
+				go func() {
+					response <- next(ctx, request)
+				}()
+				return <-response
+			}
+		})
+
+	if response := svc.HandleJSON("block", []byte(`{}`)); response.Error == nil || response.Error.Type != "timeout" {
+		t.Fatalf("Expected timeout but got %+v", response.Error)
+	}
+	if inFlight, _ := svc.Concurrency("block"); inFlight != 1 {
+		t.Fatalf("Expected the abandoned oper to hold its slot, got %d in flight", inFlight)
+	}
+	close(release)
+	waitFor(t, "the slot to be released", func() bool {
+		inFlight, _ := svc.Concurrency("block")
+		return inFlight == 0
+	})
+}
@@ -0,0 +1,28 @@
+package msvc
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+)
+
+//NewUUID returns a random (version 4) UUID as used in message headers
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate UUID: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 //version 4
+	b[8] = (b[8] & 0x3f) | 0x80 //variant RFC4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+} //NewUUID()
+
+//newInstanceID identifies this process in the response header provider
+//as "<hostname>:<pid>" so that replies from multiple instances can be told apart
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+} //newInstanceID()
@@ -0,0 +1,284 @@
+package msvc
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jansemmelink/log"
+)
+
+//FieldError describes why one field in the request data is not valid
+//according to the rules in its validate tag, e.g.:
+//
+//	Name  string   `json:"name" validate:"required,max=50"`
+//	Age   int      `json:"age" validate:"min=18,max=120"`
+//	Tags  []string `json:"tags" validate:"max=10"`
+//	Kind  string   `json:"kind" validate:"enum=small|medium|large"`
+//	Code  string   `json:"code" validate:"regex=^[A-Z]{3}$"`
+//
+//The rules are:
+//
+//	required: value may not be empty (zero, "", nil or no items)
+//	          other rules are not applied to "", nil or no items when not required,
+//	          but always to numbers, so use a pointer for an optional number
+//	min=<n>: minimum length of strings, arrays and maps or minimum value of numbers
+//	max=<n>: maximum length of strings, arrays and maps or maximum value of numbers
+//	enum=<a>|<b>|...: value must be one of the listed values
+//	regex=<expr>: string must match the regular expression (must be the last rule in the tag)
+//
+//Fields in nested structs and in structs in arrays and maps are validated as well.
+type FieldError struct {
+	Field       string `json:"field" doc:"JSON path of the field, e.g. \"items[2].name\"."`
+	Rule        string `json:"rule" doc:"Validation rule that failed, e.g. \"required\"."`
+	Description string `json:"description" doc:"Explains why the field is not valid."`
+}
+
+//fieldRules are parsed from the validate tag of a struct field
+type fieldRules struct {
+	required bool
+	min      *float64
+	max      *float64
+	enum     []string
+	regex    *regexp.Regexp
+}
+
+//parsedRules caches the fieldRules for each validate tag text
+var parsedRules sync.Map
+
+//rules returns the parsed rules of a validate tag
+func rules(tag string) (*fieldRules, error) {
+	if cached, ok := parsedRules.Load(tag); ok {
+		return cached.(*fieldRules), nil
+	}
+	fr := &fieldRules{}
+	remain := tag
+	for len(remain) > 0 {
+		var rule string
+		if strings.HasPrefix(remain, "regex=") {
+			rule, remain = remain, "" //regex may contain commas
+		} else if i := strings.Index(remain, ","); i >= 0 {
+			rule, remain = remain[:i], remain[i+1:]
+		} else {
+			rule, remain = remain, ""
+		}
+		name, value := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, value = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "required":
+			fr.required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, log.Wrapf(err, "invalid %s=\"%s\"", name, value)
+			}
+			if name == "min" {
+				fr.min = &n
+			} else {
+				fr.max = &n
+			}
+		case "enum":
+			fr.enum = strings.Split(value, "|")
+		case "regex":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, log.Wrapf(err, "invalid regex=\"%s\"", value)
+			}
+			fr.regex = re
+		case "":
+		default:
+			return nil, log.Wrapf(nil, "unknown validate rule \"%s\"", name)
+		}
+	}
+	parsedRules.Store(tag, fr)
+	return fr, nil
+} //rules()
+
+//checkValidateTags returns an error if any validate tag in the type or its nested types is not valid
+//visited contains the struct types being checked to stop recursion in recursive types
+func checkValidateTags(t reflect.Type, visited []reflect.Type) error {
+	t = elemType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, v := range visited {
+		if v == t {
+			return nil
+		}
+	}
+	visited = append(visited, t)
+	for _, f := range jsonFields(t) {
+		if _, err := rules(f.field.Tag.Get("validate")); err != nil {
+			return log.Wrapf(err, "%s.%s has invalid validate tag", t.Name(), f.field.Name)
+		}
+		if err := checkValidateTags(f.field.Type, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+} //checkValidateTags()
+
+//validateFields applies the validate tags of the request data and returns all field errors
+func validateFields(request interface{}) []FieldError {
+	fieldErrors := []FieldError{}
+	validateValue(reflect.ValueOf(request), "", &fieldErrors)
+	return fieldErrors
+}
+
+//validateValue validates the fields of structs in the value
+func validateValue(v reflect.Value, path string, fieldErrors *[]FieldError) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		for _, f := range jsonFields(v.Type()) {
+			fv, ok := fieldByIndex(v, f.field.Index)
+			if !ok {
+				continue //in nil embedded struct pointer
+			}
+			fieldPath := f.name
+			if len(path) > 0 {
+				fieldPath = path + "." + f.name
+			}
+			fr, _ := rules(f.field.Tag.Get("validate")) //tags checked in WithOper()
+			if fr != nil && !fr.apply(fv, fieldPath, fieldErrors) {
+				continue //no need to look inside invalid value
+			}
+			validateValue(fv, fieldPath, fieldErrors)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fieldErrors)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			validateValue(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k), fieldErrors)
+		}
+	}
+} //validateValue()
+
+//fieldByIndex returns the nested field or false if it is inside a nil embedded struct pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+//apply the rules to a field value and return false if any rule failed
+func (fr fieldRules) apply(v reflect.Value, path string, fieldErrors *[]FieldError) bool {
+	fail := func(rule string, format string, args ...interface{}) bool {
+		*fieldErrors = append(*fieldErrors, FieldError{
+			Field:       path,
+			Rule:        rule,
+			Description: path + " " + fmt.Sprintf(format, args...),
+		})
+		return false
+	}
+
+	if isEmptyValue(v) {
+		if fr.required {
+			return fail("required", "is required")
+		}
+		if isAbsentValue(v) {
+			return true //other rules only apply to values that are present
+		}
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	ok := true
+	if fr.min != nil || fr.max != nil {
+		var n float64
+		what := "length"
+		switch v.Kind() {
+		case reflect.String:
+			n = float64(utf8.RuneCountInString(v.String()))
+		case reflect.Slice, reflect.Array, reflect.Map:
+			n = float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, what = float64(v.Int()), "value"
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, what = float64(v.Uint()), "value"
+		case reflect.Float32, reflect.Float64:
+			n, what = v.Float(), "value"
+		}
+		if fr.min != nil && n < *fr.min {
+			ok = fail("min", "%s %g is less than %g", what, n, *fr.min)
+		}
+		if fr.max != nil && n > *fr.max {
+			ok = fail("max", "%s %g is more than %g", what, n, *fr.max)
+		}
+	}
+	if len(fr.enum) > 0 {
+		s := fmt.Sprint(v.Interface())
+		found := false
+		for _, e := range fr.enum {
+			if s == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ok = fail("enum", "\"%s\" is not one of %s", s, strings.Join(fr.enum, "|"))
+		}
+	}
+	if fr.regex != nil && v.Kind() == reflect.String && !fr.regex.MatchString(v.String()) {
+		ok = fail("regex", "\"%s\" does not match %s", v.String(), fr.regex.String())
+	}
+	return ok
+} //fieldRules.apply()
+
+//isAbsentValue is true for "", nil and values without items, but not for zero numbers and false,
+//as those may be sent by the consumer and the schema (see fieldRules.addToSchema()) applies to them
+func isAbsentValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+//isEmptyValue is true for zero numbers, false, "", nil and values without items
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
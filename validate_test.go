@@ -0,0 +1,32 @@
+package msvc
+
+import (
+	"testing"
+)
+
+type validateNumbers struct {
+	Oper
+	N    int    `json:"n" validate:"min=5"`
+	Kind int    `json:"kind" validate:"enum=1|2"`
+	Name string `json:"name" validate:"min=3"`
+	P    *int   `json:"p" validate:"min=5"`
+}
+
+func TestValidateOptionalNumbers(t *testing.T) {
+	three := 3
+	for _, c := range []struct {
+		oper   validateNumbers
+		errors int
+	}{
+		{oper: validateNumbers{N: 5, Kind: 1}, errors: 0},
+		{oper: validateNumbers{N: 0, Kind: 1}, errors: 1},
+		{oper: validateNumbers{N: 3, Kind: 1}, errors: 1},
+		{oper: validateNumbers{N: 5, Kind: 0}, errors: 1},
+		{oper: validateNumbers{N: 5, Kind: 1, Name: "ab"}, errors: 1},
+		{oper: validateNumbers{N: 5, Kind: 1, P: &three}, errors: 1},
+	} {
+		if fieldErrors := validateFields(&c.oper); len(fieldErrors) != c.errors {
+			t.Errorf("%+v: expected %d errors, got %+v", c.oper, c.errors, fieldErrors)
+		}
+	}
+}